@@ -11,7 +11,10 @@ import { ListingsTable } from "@/components/dashboard/listings-table"
 import { fetchStats, fetchListings } from "@/lib/api"
 
 export default async function DashboardPage() {
-  const [stats, listings] = await Promise.all([fetchStats(), fetchListings()])
+  // The first page of newest listings feeds the charts and the table; the
+  // table fetches further pages itself.
+  const [stats, firstPage] = await Promise.all([fetchStats(), fetchListings()])
+  const listings = firstPage.listings
 
   return (
     <div className="min-h-screen bg-background">
@@ -45,7 +48,7 @@ export default async function DashboardPage() {
           </div>
 
           {/* Listings Table */}
-          <ListingsTable initialListings={listings} />
+          <ListingsTable initialPage={firstPage} />
 
           {/* Footer */}
           <footer className="flex items-center justify-between border-t border-border/50 pt-6 pb-4">
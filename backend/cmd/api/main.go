@@ -1,25 +1,35 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"ecaycar/backend/config"
 	"ecaycar/backend/internal/api"
 	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/internal/valuation"
 )
 
 func main() {
 	cfg := config.Load()
 
-	pool, err := appdb.InitDB(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := appdb.OpenStore(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to open database: %v", err)
 	}
-	defer pool.Close()
+	defer store.Close()
 
 	log.Printf("Database connected. Starting API on :%s (env=%s)", cfg.Port, cfg.Env)
 
-	router := api.NewRouter(pool, cfg.FrontendURL)
+	// The valuation model is fitted in the background and refitted after
+	// every finished scrape run; /api/valuation answers 503 until then.
+	valuations := valuation.NewService(store)
+	go valuations.Run(ctx, valuation.RefreshInterval)
+
+	router := api.NewRouter(store, valuations, cfg.FrontendURL)
 	if err := router.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
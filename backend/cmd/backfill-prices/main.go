@@ -0,0 +1,44 @@
+// Command backfill-prices seeds price_history with the original asking price
+// of listings scraped before new listings got an initial history row. It only
+// needs running once; re-running it inserts nothing. It needs Postgres; a
+// sqlite:// DATABASE_URL is rejected.
+//
+//	go run ./cmd/backfill-prices --dry-run    # report what would be inserted
+//	go run ./cmd/backfill-prices              # insert the rows
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"ecaycar/backend/config"
+	appdb "ecaycar/backend/internal/db"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "count the rows without inserting them")
+	flag.Parse()
+
+	log.SetOutput(os.Stderr)
+
+	cfg := config.Load()
+
+	pool, err := appdb.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	inserted, unrecoverable, err := appdb.BackfillInitialPrices(context.Background(), pool, *dryRun)
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+
+	verb := "inserted"
+	if *dryRun {
+		verb = "would insert"
+	}
+	log.Printf("Done — %s: %d initial price row(s) | original price unrecoverable: %d listing(s)", verb, inserted, unrecoverable)
+}
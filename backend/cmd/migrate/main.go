@@ -0,0 +1,67 @@
+// Command migrate applies the schema migrations embedded in the db package
+// and reports which ones a database has.
+//
+//	go run ./cmd/migrate up        # apply pending migrations
+//	go run ./cmd/migrate status    # list migrations and when they were applied
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"ecaycar/backend/config"
+	appdb "ecaycar/backend/internal/db"
+)
+
+func main() {
+	log.SetOutput(os.Stderr)
+
+	if len(os.Args) != 2 || (os.Args[1] != "up" && os.Args[1] != "status") {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|status")
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+
+	pool, err := appdb.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := appdb.Migrate(ctx, pool)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Done — %d migration(s) applied.", len(applied))
+
+	case "status":
+		states, err := appdb.MigrationStatus(ctx, pool)
+		if err != nil {
+			log.Fatalf("Reading migration status: %v", err)
+		}
+		pending := 0
+		for _, s := range states {
+			status := "pending"
+			switch {
+			case !s.Known:
+				status = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05") + " (unknown to this build)"
+			case s.AppliedAt != nil:
+				status = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			default:
+				pending++
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, status)
+		}
+		fmt.Printf("%d pending\n", pending)
+	}
+}
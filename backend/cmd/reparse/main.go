@@ -0,0 +1,172 @@
+// Command reparse re-runs the current card and detail parsers over the newest
+// archived snapshot of every listing and writes back corrected values, so
+// parser fixes apply to history instead of only to future scrapes. It needs
+// Postgres; a sqlite:// DATABASE_URL is rejected.
+//
+//	go run ./cmd/reparse --dry-run        # report what would change
+//	go run ./cmd/reparse                  # apply the changes
+//	go run ./cmd/reparse --id 123456      # a single listing
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"ecaycar/backend/config"
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/internal/scraper"
+	"ecaycar/backend/models"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report field changes without writing them")
+	externalID := flag.String("id", "", "only re-parse the listing with this external_id")
+	flag.Parse()
+
+	log.SetOutput(os.Stderr)
+
+	cfg := config.Load()
+
+	pool, err := appdb.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	snaps, err := appdb.GetLatestSnapshots(ctx, pool, *externalID)
+	if err != nil {
+		log.Fatalf("Loading snapshots: %v", err)
+	}
+	log.Printf("Re-parsing %d snapshot(s) (dry-run=%v)…", len(snaps), *dryRun)
+
+	ids := make([]string, len(snaps))
+	for i, s := range snaps {
+		ids[i] = s.ExternalID
+	}
+	stored, err := appdb.GetListingsByExternalID(ctx, pool, ids)
+	if err != nil {
+		log.Fatalf("Loading listings: %v", err)
+	}
+
+	var changed, unchanged, skipped, missing, failed int
+
+	for _, snap := range snaps {
+		parsed := scraper.ParseSnapshot(snap)
+		reason, ok := scraper.Qualify(parsed)
+		prev, found := stored[snap.ExternalID]
+
+		switch {
+		case !found && ok:
+			// Previously skipped cards that now qualify are picked up, with
+			// their detail page, by the next scrape.
+			fmt.Printf("%s  now qualifies but is not in the database (%s)\n", snap.ExternalID, parsed.Title)
+			missing++
+			continue
+		case !found:
+			continue
+		case !ok:
+			fmt.Printf("%s  would now be skipped (%s) — left unchanged\n", snap.ExternalID, reason)
+			skipped++
+			continue
+		}
+
+		parsed = scraper.KeepEnrichedNames(parsed, prev)
+		if snap.DetailFields == nil && snap.DetailText == "" {
+			parsed = keepDetailFields(parsed, prev)
+		}
+
+		changes := appdb.DiffListings(prev, parsed)
+		if len(changes) == 0 {
+			unchanged++
+			continue
+		}
+
+		fmt.Printf("%s  %s\n", snap.ExternalID, prev.Title)
+		for _, c := range changes {
+			fmt.Printf("    %-15s %q → %q\n", c.Field, c.Old, c.New)
+		}
+		changed++
+
+		if *dryRun {
+			continue
+		}
+		if err := appdb.UpdateParsedFields(ctx, pool, parsed); err != nil {
+			log.Printf("ERROR %v", err)
+			failed++
+		}
+	}
+
+	verb := "updated"
+	if *dryRun {
+		verb = "would update"
+	}
+	log.Printf("Done — %s: %d | unchanged: %d | now skipped: %d | not in db: %d | errors: %d",
+		verb, changed-failed, unchanged, skipped, missing, failed)
+}
+
+// keepDetailFields fills detail-page fields the snapshot could not provide
+// (its detail fetch failed) from the stored listing, so a re-parse never wipes
+// data that an earlier run extracted successfully.
+func keepDetailFields(l, prev models.Listing) models.Listing {
+	if l.Mileage == nil {
+		l.Mileage = prev.Mileage
+	}
+	if l.Condition == "" {
+		l.Condition = prev.Condition
+	}
+	if l.Transmission == "" {
+		l.Transmission = prev.Transmission
+	}
+	if l.FuelType == "" {
+		l.FuelType = prev.FuelType
+	}
+	if l.Color == "" {
+		l.Color = prev.Color
+	}
+	if l.BodyType == "" {
+		l.BodyType = prev.BodyType
+	}
+	if l.Drive == "" {
+		l.Drive = prev.Drive
+	}
+	if l.Cylinders == "" {
+		l.Cylinders = prev.Cylinders
+	}
+	if l.Steering == "" {
+		l.Steering = prev.Steering
+	}
+	if l.InteriorColor == "" {
+		l.InteriorColor = prev.InteriorColor
+	}
+	if l.Doors == "" {
+		l.Doors = prev.Doors
+	}
+	if l.OnIsland == nil {
+		l.OnIsland = prev.OnIsland
+	}
+	if l.Attributes == nil {
+		l.Attributes = prev.Attributes
+	}
+	if l.Description == "" {
+		l.Description = prev.Description
+	}
+	if l.SellerName == "" {
+		l.SellerName = prev.SellerName
+	}
+	if l.SellerURL == "" {
+		l.SellerURL = prev.SellerURL
+	}
+	if l.PostedAt == nil {
+		l.PostedAt = prev.PostedAt
+	}
+	if l.AdUpdatedAt == nil {
+		l.AdUpdatedAt = prev.AdUpdatedAt
+	}
+	l.Images = scraper.MergeImages(l.Images, prev.Images)
+	return l
+}
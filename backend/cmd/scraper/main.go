@@ -2,60 +2,264 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
+	"time"
 
 	"ecaycar/backend/config"
 	appdb "ecaycar/backend/internal/db"
 	"ecaycar/backend/internal/scraper"
+	"ecaycar/backend/internal/valuation"
+	"ecaycar/backend/models"
 )
 
+// delistMinSeenRatio guards the delisting sweep: if a "complete" category crawl
+// saw fewer than this fraction of the category's currently active listings,
+// something went wrong (e.g. the site served a truncated result set) and
+// nothing in that category is deactivated.
+const delistMinSeenRatio = 0.5
+
+// exitBlocked is the exit status when the site served a bot challenge, so
+// alerting can tell "we got blocked" (3) from other failures (1).
+const exitBlocked = 3
+
 func main() {
 	log.SetOutput(os.Stderr)
 
 	cfg := config.Load()
 
-	pool, err := appdb.InitDB(cfg)
+	ctx := context.Background()
+
+	store, err := appdb.OpenStore(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to open database: %v", err)
 	}
-	defer pool.Close()
+	defer store.Close()
 	log.Println("Database connected.")
 
-	ctx := context.Background()
-
-	listings, err := scraper.Scrape()
+	rec := scraper.NewRecorder()
+	runID, err := store.StartScrapeRun(ctx, rec.Run())
 	if err != nil {
-		log.Fatalf("Scrape failed: %v", err)
+		// The ledger is diagnostic only — never block a scrape on it.
+		log.Printf("WARNING: could not record scrape run: %v", err)
+	}
+
+	src := scraper.LiveSource()
+	if dir := os.Getenv("REPLAY_DIR"); dir != "" {
+		rs, err := scraper.NewReplaySource(dir)
+		if err != nil {
+			finishRun(ctx, store, runID, rec, err)
+			log.Fatalf("Replay source: %v", err)
+		}
+		log.Printf("Replaying archived pages from %s", dir)
+		src = rs
+	}
+	defer func() { _ = src.Close() }()
+
+	opts := scraper.Options{
+		Source:         src,
+		Recorder:       rec,
+		DetailWorkers:  cfg.DetailWorkers,
+		DetailInterval: time.Duration(cfg.DetailIntervalMS) * time.Millisecond,
+		Retry: scraper.RetryPolicy{
+			Attempts: cfg.RetryAttempts,
+			Base:     time.Duration(cfg.RetryBaseMS) * time.Millisecond,
+			Max:      time.Duration(cfg.RetryMaxMS) * time.Millisecond,
+			Jitter:   cfg.RetryJitter,
+		},
+	}
+	if cfg.ArchiveSnapshots {
+		opts.Archive = func(s models.ListingSnapshot) {
+			if err := store.InsertListingSnapshot(ctx, runID, s); err != nil {
+				log.Printf("WARNING: %v", err)
+			}
+		}
+	}
+
+	if cfg.Incremental {
+		known, err := store.GetKnownListings(ctx)
+		if err != nil {
+			finishRun(ctx, store, runID, rec, err)
+			log.Fatalf("Loading known listings: %v", err)
+		}
+		log.Printf("Incremental mode: %d known listing(s).", len(known))
+		opts.Known = known
+		opts.StopAfterStalePages = cfg.IncrementalStopPages
+	}
+
+	res, scrapeErr := scraper.Scrape(opts)
+	blocked := errors.Is(scrapeErr, scraper.ErrBlocked)
+	if scrapeErr != nil && !blocked {
+		finishRun(ctx, store, runID, rec, scrapeErr)
+		log.Fatalf("Scrape failed: %v", scrapeErr)
+	}
+	listings := res.Listings
+	if len(listings) == 0 && len(res.Unchanged) == 0 {
+		if blocked {
+			finishRun(ctx, store, runID, rec, scrapeErr)
+			log.Printf("Scrape blocked before any listings were extracted: %v", scrapeErr)
+			os.Exit(exitBlocked)
+		}
+		finishRun(ctx, store, runID, rec, errors.New("no listings extracted"))
+		log.Fatal("No listings extracted — selectors may need updating.")
 	}
-	if len(listings) == 0 {
-		log.Fatal("No listings extracted — selectors may need updating or Cloudflare blocked the request.")
+	if blocked {
+		// Keep what was scraped before the block; the run is partial, so the
+		// delisting sweep below is skipped.
+		log.Printf("WARNING: %v — saving the %d listing(s) scraped before the block", scrapeErr, len(listings))
 	}
 	log.Printf("Scraped %d listing(s). Running AI enrichment…", len(listings))
 
-	listings = scraper.EnrichListings(ctx, listings, cfg.GitHubToken)
+	listings = scraper.EnrichListings(ctx, listings, cfg.GitHubToken, rec)
 
 	log.Printf("Upserting %d listing(s) to database…", len(listings))
 
-	var inserted, updated, priceChanged int
+	// The upsert is all-or-nothing: on failure no listing or history row from
+	// this run is written, and the next run picks everything up again.
+	results, err := store.UpsertListings(ctx, listings)
+	if err != nil {
+		for range listings {
+			rec.UpsertFailed()
+		}
+		finishRun(ctx, store, runID, rec, err)
+		log.Fatalf("Upsert failed, nothing was written: %v", err)
+	}
+	for _, r := range results {
+		rec.Upserted(r.Inserted, r.PriceChanged)
+	}
 
-	for _, l := range listings {
-		result, err := appdb.UpsertListing(ctx, pool, l)
-		if err != nil {
-			log.Printf("ERROR upserting %s (%s): %v", l.ExternalID, l.Title, err)
+	if len(res.Unchanged) > 0 {
+		if _, err := store.TouchListings(ctx, res.Unchanged); err != nil {
+			log.Printf("ERROR marking %d unchanged listing(s) as seen: %v", len(res.Unchanged), err)
+		}
+	}
+
+	refreshSellers(ctx, store, listings)
+
+	// A replayed archive says nothing about what is live on the site today.
+	if src.Live() {
+		rec.SetDelisted(int(sweepDelisted(ctx, store, res)))
+	} else {
+		log.Println("Replay run — skipping delisting sweep.")
+	}
+	scoreDeals(ctx, store)
+	if n, err := store.MaterializeDailyInventory(ctx); err != nil {
+		log.Printf("WARNING: %v", err)
+	} else {
+		log.Printf("Daily inventory: %d row(s) materialized.", n)
+	}
+	finishRun(ctx, store, runID, rec, scrapeErr)
+
+	if cfg.ArchiveSnapshots {
+		retention := time.Duration(cfg.SnapshotRetentionDays) * 24 * time.Hour
+		if n, err := store.PruneListingSnapshots(ctx, retention); err != nil {
+			log.Printf("WARNING: %v", err)
+		} else if n > 0 {
+			log.Printf("Pruned %d snapshot(s) older than %d day(s).", n, cfg.SnapshotRetentionDays)
+		}
+	}
+
+	run := rec.Run()
+	log.Printf("Done — inserted: %d | updated: %d (price changed: %d) | delisted: %d | errors: %d",
+		run.Inserted, run.Updated, run.PriceChanged, run.Delisted, run.UpsertErrors)
+
+	if blocked {
+		os.Exit(exitBlocked)
+	}
+}
+
+// finishRun stamps the run as finished and writes its counters to the
+// scrape_runs ledger. runID is empty when the run could not be recorded.
+func finishRun(ctx context.Context, store appdb.ScrapeStore, runID string, rec *scraper.Recorder, runErr error) {
+	rec.Finish(runErr)
+	if runID == "" {
+		return
+	}
+	if err := store.FinishScrapeRun(ctx, runID, rec.Run()); err != nil {
+		log.Printf("WARNING: could not record scrape run: %v", err)
+	}
+}
+
+// refreshSellers records the sellers of the scraped listings and re-classifies
+// every seller as dealer or private, since listing volume changes each run.
+// Failures are logged and never fail the run.
+func refreshSellers(ctx context.Context, store appdb.ScrapeStore, listings []models.Listing) {
+	if err := store.UpsertSellers(ctx, scraper.SellersFromListings(listings)); err != nil {
+		log.Printf("WARNING: %v", err)
+		return
+	}
+
+	sellers, err := store.GetSellers(ctx, "")
+	if err != nil {
+		log.Printf("WARNING: %v", err)
+		return
+	}
+	dealers := 0
+	for _, s := range sellers {
+		t := scraper.ClassifySeller(s)
+		if t == models.SellerTypeDealer {
+			dealers++
+		}
+		if t == s.SellerType {
 			continue
 		}
-		switch {
-		case result.Inserted:
-			inserted++
-		case result.PriceChanged:
-			priceChanged++
-			updated++
-		default:
-			updated++
+		if err := store.SetSellerType(ctx, s.ProfileURL, t); err != nil {
+			log.Printf("WARNING: %v", err)
 		}
 	}
+	log.Printf("Sellers: %d known, %d classified as dealer(s).", len(sellers), dealers)
+}
 
-	log.Printf("Done — inserted: %d | updated: %d (price changed: %d) | errors: %d",
-		inserted, updated, priceChanged, len(listings)-inserted-updated)
+// scoreDeals refits the valuation model on the active listings and stores
+// the deal score of every listing it can rate, clearing the rest. Failures
+// are logged and never fail the run.
+func scoreDeals(ctx context.Context, store appdb.ScrapeStore) {
+	listings, _, err := store.GetListings(ctx, appdb.ListingFilter{Category: valuation.Category})
+	if err != nil {
+		log.Printf("WARNING: deal scoring skipped: %v", err)
+		return
+	}
+	scores := valuation.Fit(listings, time.Now()).ScoreDeals(listings)
+	if err := store.SetDealScores(ctx, scores); err != nil {
+		log.Printf("WARNING: %v", err)
+		return
+	}
+	log.Printf("Deal scores: %d of %d active listing(s) scored.", len(scores), len(listings))
+}
+
+// sweepDelisted deactivates listings that were not seen in this run, one
+// category at a time. Categories crawled only partially, or that saw
+// suspiciously few listings, are skipped. Returns the total deactivated.
+func sweepDelisted(ctx context.Context, store appdb.ScrapeStore, res scraper.Result) int64 {
+	var total int64
+	for _, cr := range res.Categories {
+		total += sweepCategory(ctx, store, cr)
+	}
+	return total
+}
+
+// sweepCategory runs the delisting sweep for a single category.
+func sweepCategory(ctx context.Context, store appdb.ScrapeStore, cr scraper.CategoryResult) int64 {
+	if !cr.Complete {
+		log.Printf("[%s] Partial crawl — skipping delisting sweep.", cr.Category)
+		return 0
+	}
+
+	active, err := store.CountActiveListings(ctx, cr.Category)
+	if err != nil {
+		log.Printf("[%s] ERROR counting active listings — skipping delisting sweep: %v", cr.Category, err)
+		return 0
+	}
+	if active > 0 && float64(len(cr.SeenIDs)) < float64(active)*delistMinSeenRatio {
+		log.Printf("[%s] Saw only %d of %d active listings — skipping delisting sweep.", cr.Category, len(cr.SeenIDs), active)
+		return 0
+	}
+
+	n, err := store.DeactivateUnseen(ctx, cr.Category, cr.SeenIDs)
+	if err != nil {
+		log.Printf("[%s] ERROR during delisting sweep: %v", cr.Category, err)
+		return 0
+	}
+	return n
 }
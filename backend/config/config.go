@@ -4,17 +4,51 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all runtime configuration loaded from environment variables.
 type Config struct {
+	// DatabaseURL is a Postgres connection string, or sqlite://<path> for a
+	// local SQLite file.
 	DatabaseURL string
 	Port        string
 	FrontendURL string
 	Env         string
 	GitHubToken string
+
+	// Incremental makes the scraper skip detail pages for listings whose
+	// card price and title match what is already stored.
+	Incremental bool
+	// IncrementalStopPages stops pagination after that many consecutive
+	// pages with nothing new or changed (incremental mode only; 0 = never).
+	IncrementalStopPages int
+
+	// DetailWorkers is how many detail pages the scraper fetches at once.
+	DetailWorkers int
+	// DetailIntervalMS is the minimum gap between any two detail
+	// navigations across all workers.
+	DetailIntervalMS int
+
+	// RetryAttempts, RetryBaseMS, RetryMaxMS and RetryJitter shape the
+	// backoff applied to browser navigations and evaluations.
+	RetryAttempts int
+	RetryBaseMS   int
+	RetryMaxMS    int
+	RetryJitter   float64
+
+	// ArchiveSnapshots enables storing raw card/detail pages per scrape run.
+	ArchiveSnapshots bool
+	// SnapshotRetentionDays is how long archived snapshots are kept. The
+	// newest snapshot of each listing is always kept regardless.
+	SnapshotRetentionDays int
+
+	// RequireMigrations makes the API and scraper refuse to start while
+	// schema migrations are pending, instead of only logging a warning.
+	RequireMigrations bool
 }
 
 // Load reads the .env file (if present) then maps env vars into a Config.
@@ -30,6 +64,22 @@ func Load() *Config {
 		FrontendURL: getEnvOrDefault("FRONTEND_URL", "http://localhost:3000"),
 		Env:         getEnvOrDefault("ENV", "development"),
 		GitHubToken: os.Getenv("GITHUB_TOKEN"),
+
+		Incremental:          strings.EqualFold(os.Getenv("INCREMENTAL"), "true"),
+		IncrementalStopPages: getEnvIntOrDefault("INCREMENTAL_STOP_PAGES", 0),
+
+		DetailWorkers:    getEnvIntOrDefault("DETAIL_WORKERS", 1),
+		DetailIntervalMS: getEnvIntOrDefault("DETAIL_INTERVAL_MS", 1000),
+
+		RetryAttempts: getEnvIntOrDefault("SCRAPER_RETRY_ATTEMPTS", 3),
+		RetryBaseMS:   getEnvIntOrDefault("SCRAPER_RETRY_BASE_MS", 1000),
+		RetryMaxMS:    getEnvIntOrDefault("SCRAPER_RETRY_MAX_MS", 15000),
+		RetryJitter:   getEnvFloatOrDefault("SCRAPER_RETRY_JITTER", 0.3),
+
+		ArchiveSnapshots:      !strings.EqualFold(os.Getenv("ARCHIVE_SNAPSHOTS"), "false"),
+		SnapshotRetentionDays: getEnvIntOrDefault("SNAPSHOT_RETENTION_DAYS", 14),
+
+		RequireMigrations: strings.EqualFold(os.Getenv("REQUIRE_MIGRATIONS"), "true"),
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -45,3 +95,23 @@ func getEnvOrDefault(key, def string) string {
 	}
 	return def
 }
+
+func getEnvIntOrDefault(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+		log.Printf("config: %s=%q is not an integer — using %d", key, v, def)
+	}
+	return def
+}
+
+func getEnvFloatOrDefault(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+		log.Printf("config: %s=%q is not a number — using %g", key, v, def)
+	}
+	return def
+}
@@ -6,17 +6,18 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgxpool"
+
+	appdb "ecaycar/backend/internal/db"
 )
 
 // Health handles GET /health.
-// It pings the database and returns 200 OK or 500 if the DB is unreachable.
-func Health(pool *pgxpool.Pool) gin.HandlerFunc {
+// It pings the store and returns 200 OK or 500 if it is unreachable.
+func Health(store appdb.ListingStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
 		defer cancel()
 
-		if err := pool.Ping(ctx); err != nil {
+		if err := store.Ping(ctx); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"status": "error",
 				"error":  err.Error(),
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/models"
+)
+
+// ListingHistory handles GET /api/listings/:id/history.
+// Returns every recorded field-level change of the listing, newest first, as
+// { "data": [...], "error": null }. :id is the listing's UUID or its
+// ecaytrade external_id; an unknown listing is a 404.
+func ListingHistory(store appdb.ListingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changes, err := store.GetListingChanges(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, appdb.ErrListingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if changes == nil {
+			changes = make([]models.ListingChange, 0)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  changes,
+			"error": nil,
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/models"
+)
+
+// ListingDetail handles GET /api/listings/:id.
+// Returns one listing, active or not, with its price history (oldest first),
+// days on market and the most similar active listings (same make and model,
+// nearest in year and mileage) as { "data": {...}, "error": null }. :id is the
+// listing's UUID or its ecaytrade external_id; an unknown listing is a 404.
+func ListingDetail(store appdb.ListingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		detail, err := appdb.GetListingDetail(c.Request.Context(), store, c.Param("id"))
+		if errors.Is(err, appdb.ErrListingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if detail.PriceHistory == nil {
+			detail.PriceHistory = make([]models.PricePoint, 0)
+		}
+		if detail.Similar == nil {
+			detail.Similar = make([]models.Listing, 0)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  detail,
+			"error": nil,
+		})
+	}
+}
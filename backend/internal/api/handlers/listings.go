@@ -2,20 +2,50 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	appdb "ecaycar/backend/internal/db"
 	"ecaycar/backend/models"
 )
 
+// defaultListingsLimit is the page size of GET /api/listings without ?limit=;
+// maxListingsLimit caps ?limit=.
+const (
+	defaultListingsLimit = 100
+	maxListingsLimit     = 500
+)
+
 // Listings handles GET /api/listings.
-// Returns all active listings as { "data": [...], "error": null }.
-func Listings(pool *pgxpool.Pool) gin.HandlerFunc {
+// Returns the active listings matching the query as
+// { "data": [...], "total": n, "limit": n, "offset": n, "error": null }, where
+// total counts every match across pages. Filters:
+//
+//	category, seller_type                           exact match
+//	make, model, body_type, transmission, fuel_type case-insensitive match
+//	location                                        case-insensitive substring
+//	on_island                                       true or false
+//	year_min/max, price_min/max, mileage_min/max    inclusive ranges
+//
+// ?sort=price|year|mileage|first_seen|deal_score with ?order=asc|desc orders
+// the result (newest first by default). Results are paged: ?limit= sets the
+// page size (default 100, capped at 500) and ?offset= skips that many matches.
+func Listings(store appdb.ListingStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		listings, err := appdb.GetListings(c.Request.Context(), pool)
+		f, err := listingFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		listings, total, err := store.GetListings(c.Request.Context(), f)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"data":  nil,
@@ -30,8 +60,96 @@ func Listings(pool *pgxpool.Pool) gin.HandlerFunc {
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"data":  listings,
-			"error": nil,
+			"data":   listings,
+			"total":  total,
+			"limit":  f.Limit,
+			"offset": f.Offset,
+			"error":  nil,
 		})
 	}
 }
+
+// listingFilter reads GET /api/listings query parameters into a filter. The
+// error describes the first invalid parameter.
+func listingFilter(c *gin.Context) (appdb.ListingFilter, error) {
+	f := appdb.ListingFilter{
+		Category:     c.Query("category"),
+		SellerType:   c.Query("seller_type"),
+		Make:         c.Query("make"),
+		Model:        c.Query("model"),
+		BodyType:     c.Query("body_type"),
+		Transmission: c.Query("transmission"),
+		FuelType:     c.Query("fuel_type"),
+		Location:     c.Query("location"),
+		Sort:         c.Query("sort"),
+	}
+	if f.SellerType != "" && f.SellerType != models.SellerTypeDealer && f.SellerType != models.SellerTypePrivate {
+		return f, errors.New("seller_type must be dealer or private")
+	}
+	if f.Sort != "" && !appdb.ValidSort(f.Sort) {
+		return f, errors.New("sort must be price, year, mileage, first_seen or deal_score")
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		return f, errors.New("order must be asc or desc")
+	}
+
+	var err error
+	if f.OnIsland, err = optionalParam(c, "on_island", strconv.ParseBool); err != nil {
+		return f, err
+	}
+	for _, p := range []struct {
+		name string
+		dst  **int
+	}{
+		{"year_min", &f.YearMin},
+		{"year_max", &f.YearMax},
+		{"mileage_min", &f.MileageMin},
+		{"mileage_max", &f.MileageMax},
+	} {
+		if *p.dst, err = optionalParam(c, p.name, strconv.Atoi); err != nil {
+			return f, err
+		}
+	}
+	parseFloat := func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+	if f.PriceMin, err = optionalParam(c, "price_min", parseFloat); err != nil {
+		return f, err
+	}
+	if f.PriceMax, err = optionalParam(c, "price_max", parseFloat); err != nil {
+		return f, err
+	}
+
+	f.Limit = defaultListingsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return f, errors.New("limit must be a positive integer")
+		}
+		f.Limit = min(n, maxListingsLimit)
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, errors.New("offset must be a non-negative integer")
+		}
+		f.Offset = n
+	}
+	return f, nil
+}
+
+// optionalParam parses query parameter name with parse, returning nil when it
+// is absent.
+func optionalParam[T any](c *gin.Context, name string, parse func(string) (T, error)) (*T, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	parsed, err := parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/models"
+)
+
+const (
+	defaultScrapeRunsLimit = 50
+	maxScrapeRunsLimit     = 500
+)
+
+// ScrapeRuns handles GET /api/scrape-runs.
+// Returns the scrape run ledger newest-first as { "data": [...], "error": null }.
+// The optional ?limit= query parameter caps the number of runs (default 50).
+func ScrapeRuns(store appdb.ListingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultScrapeRunsLimit
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"data":  nil,
+					"error": "limit must be a positive integer",
+				})
+				return
+			}
+			limit = min(n, maxScrapeRunsLimit)
+		}
+
+		runs, err := store.GetScrapeRuns(c.Request.Context(), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if runs == nil {
+			runs = make([]models.ScrapeRun, 0)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  runs,
+			"error": nil,
+		})
+	}
+}
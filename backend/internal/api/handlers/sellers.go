@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/models"
+)
+
+// Sellers handles GET /api/sellers.
+// Returns every seller with inventory count, average price and average days
+// on market as { "data": [...], "error": null }. ?seller_type=dealer (or
+// private) limits the result to one kind of seller, as on /api/listings.
+func Sellers(store appdb.ListingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sellerType := c.Query("seller_type")
+		if sellerType != "" && sellerType != models.SellerTypeDealer && sellerType != models.SellerTypePrivate {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"data":  nil,
+				"error": "seller_type must be dealer or private",
+			})
+			return
+		}
+
+		sellers, err := store.GetSellers(c.Request.Context(), sellerType)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if sellers == nil {
+			sellers = make([]models.Seller, 0)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  sellers,
+			"error": nil,
+		})
+	}
+}
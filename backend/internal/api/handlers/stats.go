@@ -5,16 +5,16 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	appdb "ecaycar/backend/internal/db"
 )
 
 // Stats handles GET /api/stats.
 // Returns pre-computed dashboard statistics as { "data": {...}, "error": null }.
-func Stats(pool *pgxpool.Pool) gin.HandlerFunc {
+// ?category=boats limits the statistics to one category.
+func Stats(store appdb.ListingStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		stats, err := appdb.GetStats(c.Request.Context(), pool)
+		stats, err := store.GetStats(c.Request.Context(), c.Query("category"))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"data":  nil,
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/models"
+)
+
+// Trends handles GET /api/trends.
+// Returns market figures per bucket, oldest first, as
+// { "data": [...], "error": null }: active inventory with its median and
+// average price on the bucket's last day, and the listings new and delisted
+// within it. ?granularity=day|week|month sets the bucket size (default week);
+// ?category=, ?make= and ?body_type= narrow the listings counted.
+func Trends(store appdb.ListingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f := appdb.TrendFilter{
+			Granularity: c.DefaultQuery("granularity", appdb.GranularityWeek),
+			Category:    c.Query("category"),
+			Make:        c.Query("make"),
+			BodyType:    c.Query("body_type"),
+		}
+		if !appdb.ValidGranularity(f.Granularity) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"data":  nil,
+				"error": "granularity must be day, week or month",
+			})
+			return
+		}
+
+		trends, err := store.GetTrends(c.Request.Context(), f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if trends == nil {
+			trends = make([]models.TrendBucket, 0)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  trends,
+			"error": nil,
+		})
+	}
+}
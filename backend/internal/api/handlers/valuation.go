@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"ecaycar/backend/internal/valuation"
+)
+
+// Valuation handles GET /api/valuation.
+// Estimates the fair asking price of a car from ?make=, ?model=, ?year=
+// (required) and ?mileage=, optionally refined by ?body_type= and
+// ?condition=, as { "data": {...}, "error": null }. The estimate comes with a
+// 90% interval, the model level and confidence tier, and the comparable
+// listings nearest the car. 404 means there are too few listings to value
+// against; 503 means the model has not been fitted yet.
+func Valuation(svc *valuation.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := valuation.Query{
+			Make:      c.Query("make"),
+			Model:     c.Query("model"),
+			BodyType:  c.Query("body_type"),
+			Condition: c.Query("condition"),
+		}
+		year, err := strconv.Atoi(c.Query("year"))
+		if err != nil || year < 1900 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"data":  nil,
+				"error": "year must be a model year",
+			})
+			return
+		}
+		q.Year = year
+		if q.Mileage, err = optionalParam(c, "mileage", strconv.Atoi); err != nil || (q.Mileage != nil && *q.Mileage < 0) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"data":  nil,
+				"error": "mileage must be a non-negative integer",
+			})
+			return
+		}
+
+		v, err := svc.Estimate(q)
+		if errors.Is(err, valuation.ErrNotFitted) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, valuation.ErrNoModel) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"data":  nil,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  v,
+			"error": nil,
+		})
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"ecaycar/backend/internal/api/handlers"
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/internal/valuation"
 )
 
 // NewRouter creates and configures the Gin engine with all routes and middleware.
-func NewRouter(pool *pgxpool.Pool, frontendURL string) *gin.Engine {
+// Handlers read through store, so any ListingStore implementation can back the API;
+// /api/valuation is served by valuations, which the caller keeps fitted.
+func NewRouter(store appdb.ListingStore, valuations *valuation.Service, frontendURL string) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
@@ -30,12 +33,18 @@ func NewRouter(pool *pgxpool.Pool, frontendURL string) *gin.Engine {
 	})
 
 	// ── Routes ──
-	r.GET("/health", handlers.Health(pool))
+	r.GET("/health", handlers.Health(store))
 
 	api := r.Group("/api")
 	{
-		api.GET("/listings", handlers.Listings(pool))
-		api.GET("/stats", handlers.Stats(pool))
+		api.GET("/listings", handlers.Listings(store))
+		api.GET("/listings/:id", handlers.ListingDetail(store))
+		api.GET("/listings/:id/history", handlers.ListingHistory(store))
+		api.GET("/stats", handlers.Stats(store))
+		api.GET("/trends", handlers.Trends(store))
+		api.GET("/scrape-runs", handlers.ScrapeRuns(store))
+		api.GET("/sellers", handlers.Sellers(store))
+		api.GET("/valuation", handlers.Valuation(valuations))
 	}
 
 	return r
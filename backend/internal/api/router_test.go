@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/internal/valuation"
+	"ecaycar/backend/models"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+// envelope is the JSON shape every API response shares.
+type envelope struct {
+	Data   json.RawMessage `json:"data"`
+	Total  *int            `json:"total"`
+	Limit  *int            `json:"limit"`
+	Offset *int            `json:"offset"`
+	Error  *string         `json:"error"`
+}
+
+func intp(v int) *int { return &v }
+
+// newTestRouter returns a router over a MemoryStore seeded with listings.
+func newTestRouter(t *testing.T, listings ...models.Listing) (*gin.Engine, *appdb.MemoryStore) {
+	t.Helper()
+	store := appdb.NewMemoryStore()
+	if _, err := store.UpsertListings(context.Background(), listings); err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(store, valuation.NewService(store), "*"), store
+}
+
+func get(t *testing.T, r http.Handler, url string) (int, envelope) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	var env envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("GET %s: decode %q: %v", url, w.Body.String(), err)
+	}
+	return w.Code, env
+}
+
+func seedListings() []models.Listing {
+	yes, no := true, false
+	return []models.Listing{
+		{ExternalID: "1", Title: "2018 Toyota Camry", Make: "Toyota", Model: "Camry", Year: intp(2018), Mileage: intp(48000), Price: 15000, BodyType: "Sedan", Location: "George Town", OnIsland: &yes},
+		{ExternalID: "2", Title: "2015 Toyota RAV4", Make: "Toyota", Model: "RAV4", Year: intp(2015), Mileage: intp(90000), Price: 11000, BodyType: "SUV", Location: "West Bay", OnIsland: &yes},
+		{ExternalID: "3", Title: "2020 Honda Civic", Make: "Honda", Model: "Civic", Year: intp(2020), Mileage: intp(20000), Price: 21000, BodyType: "Sedan", Location: "George Town", OnIsland: &no},
+		{ExternalID: "4", Title: "2012 Ford Ranger", Make: "Ford", Model: "Ranger", Year: intp(2012), Price: 9000, BodyType: "Pickup"},
+		{ExternalID: "5", Title: "2019 Sea Ray 240", Category: "boats", Year: intp(2019), Price: 60000},
+	}
+}
+
+func externalIDs(t *testing.T, data json.RawMessage) []string {
+	t.Helper()
+	var listings []models.Listing
+	if err := json.Unmarshal(data, &listings); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(listings))
+	for _, l := range listings {
+		ids = append(ids, l.ExternalID)
+	}
+	return ids
+}
+
+func TestListingsFilters(t *testing.T) {
+	r, _ := newTestRouter(t, seedListings()...)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?sort=price", []string{"4", "2", "1", "3", "5"}},
+		{"?sort=price&order=desc", []string{"5", "3", "1", "2", "4"}},
+		{"?category=autos&sort=price", []string{"4", "2", "1", "3"}},
+		{"?make=toyota&sort=year", []string{"2", "1"}},
+		{"?body_type=SEDAN&sort=price", []string{"1", "3"}},
+		{"?location=george&sort=price", []string{"1", "3"}},
+		{"?on_island=false", []string{"3"}},
+		{"?price_min=10000&price_max=20000&sort=price", []string{"2", "1"}},
+		{"?year_min=2015&year_max=2018&sort=year", []string{"2", "1"}},
+		{"?mileage_max=50000&sort=mileage", []string{"3", "1"}},
+		{"?make=lada", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			code, env := get(t, r, "/api/listings"+tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, error = %v", code, env.Error)
+			}
+			got := externalIDs(t, env.Data)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ids = %v, want %v", got, tt.want)
+				}
+			}
+			if env.Total == nil || *env.Total != len(tt.want) {
+				t.Errorf("total = %v, want %d", env.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestListingsPagination(t *testing.T) {
+	r, _ := newTestRouter(t, seedListings()...)
+
+	code, env := get(t, r, "/api/listings?sort=price&limit=2&offset=1")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if got := externalIDs(t, env.Data); len(got) != 2 || got[0] != "2" || got[1] != "1" {
+		t.Errorf("page = %v, want [2 1]", got)
+	}
+	if *env.Total != 5 || *env.Limit != 2 || *env.Offset != 1 {
+		t.Errorf("total/limit/offset = %d/%d/%d, want 5/2/1", *env.Total, *env.Limit, *env.Offset)
+	}
+
+	_, env = get(t, r, "/api/listings")
+	if env.Limit == nil || *env.Limit != 100 {
+		t.Errorf("default limit = %v, want 100", env.Limit)
+	}
+	_, env = get(t, r, "/api/listings?limit=10000")
+	if env.Limit == nil || *env.Limit != 500 {
+		t.Errorf("capped limit = %v, want 500", env.Limit)
+	}
+
+	_, env = get(t, r, "/api/listings?offset=10")
+	if got := externalIDs(t, env.Data); len(got) != 0 || *env.Total != 5 {
+		t.Errorf("past the end: %v (total %d), want an empty page of 5", got, *env.Total)
+	}
+}
+
+func TestListingsBadParams(t *testing.T) {
+	r, _ := newTestRouter(t)
+	for _, q := range []string{
+		"limit=0", "limit=x", "offset=-1", "sort=colour", "order=up",
+		"seller_type=broker", "on_island=maybe", "year_min=new", "price_max=cheap",
+	} {
+		code, env := get(t, r, "/api/listings?"+q)
+		if code != http.StatusBadRequest || env.Error == nil {
+			t.Errorf("?%s: status = %d, error = %v; want 400 with an error", q, code, env.Error)
+		}
+	}
+}
+
+func TestListingNotFound(t *testing.T) {
+	r, _ := newTestRouter(t, seedListings()...)
+	for _, url := range []string{
+		"/api/listings/999999",
+		"/api/listings/00000000-0000-0000-0000-000000000000",
+		"/api/listings/999999/history",
+		"/api/listings/00000000-0000-0000-0000-000000000000/history",
+	} {
+		code, env := get(t, r, url)
+		if code != http.StatusNotFound || env.Error == nil || string(env.Data) != "null" {
+			t.Errorf("GET %s: status = %d, data = %s, error = %v; want 404", url, code, env.Data, env.Error)
+		}
+	}
+}
+
+func TestListingDetailAndHistory(t *testing.T) {
+	listings := seedListings()
+	r, store := newTestRouter(t, listings...)
+
+	changed := listings[0]
+	changed.Price, changed.Mileage = 14000, intp(49000)
+	if _, err := store.UpsertListings(context.Background(), []models.Listing{changed}); err != nil {
+		t.Fatal(err)
+	}
+
+	code, env := get(t, r, "/api/listings/1")
+	if code != http.StatusOK {
+		t.Fatalf("detail status = %d, error = %v", code, env.Error)
+	}
+	var detail models.ListingDetail
+	if err := json.Unmarshal(env.Data, &detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.Price != 14000 || len(detail.PriceHistory) != 2 || detail.PriceHistory[0].Price != 15000 {
+		t.Errorf("price %v, history %+v; want 14000 after 15000", detail.Price, detail.PriceHistory)
+	}
+
+	code, env = get(t, r, "/api/listings/"+detail.ID+"/history")
+	if code != http.StatusOK {
+		t.Fatalf("history status = %d, error = %v", code, env.Error)
+	}
+	var changes []models.ListingChange
+	if err := json.Unmarshal(env.Data, &changes); err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]bool{}
+	for _, c := range changes {
+		fields[c.Field] = true
+	}
+	if len(changes) != 2 || !fields["price"] || !fields["mileage"] {
+		t.Errorf("changes = %+v, want price and mileage", changes)
+	}
+
+	_, env = get(t, r, "/api/listings/2/history")
+	if string(env.Data) != "[]" {
+		t.Errorf("unchanged listing history = %s, want []", env.Data)
+	}
+}
+
+func TestTrendsGranularity(t *testing.T) {
+	r, _ := newTestRouter(t, seedListings()...)
+
+	for _, g := range []string{"", "day", "week", "month"} {
+		url := "/api/trends"
+		if g != "" {
+			url += "?granularity=" + g
+		}
+		code, env := get(t, r, url)
+		if code != http.StatusOK {
+			t.Errorf("granularity %q: status = %d, error = %v", g, code, env.Error)
+		}
+		var buckets []models.TrendBucket
+		if err := json.Unmarshal(env.Data, &buckets); err != nil || len(buckets) == 0 {
+			t.Errorf("granularity %q: buckets = %s (%v), want at least one", g, env.Data, err)
+		}
+	}
+	for _, g := range []string{"hour", "year", "WEEK"} {
+		code, env := get(t, r, "/api/trends?granularity="+g)
+		if code != http.StatusBadRequest || env.Error == nil {
+			t.Errorf("granularity %q: status = %d, want 400", g, code)
+		}
+	}
+}
+
+func TestSellersType(t *testing.T) {
+	r, _ := newTestRouter(t, seedListings()...)
+	for _, q := range []string{"", "?seller_type=dealer", "?seller_type=private"} {
+		code, env := get(t, r, "/api/sellers"+q)
+		if code != http.StatusOK || string(env.Data) == "null" {
+			t.Errorf("GET /api/sellers%s: status = %d, data = %s; want 200 with an array", q, code, env.Data)
+		}
+	}
+	for _, q := range []string{"seller_type=broker", "seller_type=Dealer"} {
+		code, env := get(t, r, "/api/sellers?"+q)
+		if code != http.StatusBadRequest || env.Error == nil {
+			t.Errorf("?%s: status = %d, error = %v; want 400 with an error", q, code, env.Error)
+		}
+	}
+}
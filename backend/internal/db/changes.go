@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// resolveListingID returns the UUID of the listing identified by id, which
+// may be either its UUID or its ecaytrade external_id.
+func resolveListingID(ctx context.Context, pool *pgxpool.Pool, id string) (string, error) {
+	var listingID string
+	err := pool.QueryRow(ctx,
+		`SELECT id FROM listings WHERE id::text = $1 OR external_id = $1 LIMIT 1`,
+		id,
+	).Scan(&listingID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrListingNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("look up listing %s: %w", id, err)
+	}
+	return listingID, nil
+}
+
+// GetListingChanges returns the recorded field-level changes of one listing,
+// newest first. id may be the listing's UUID or its external_id; an unknown
+// listing yields ErrListingNotFound.
+func GetListingChanges(ctx context.Context, pool *pgxpool.Pool, id string) ([]models.ListingChange, error) {
+	listingID, err := resolveListingID(ctx, pool, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT field, COALESCE(old_value, ''), COALESCE(new_value, ''), changed_at
+		FROM listing_changes
+		WHERE listing_id = $1
+		ORDER BY changed_at DESC, field
+	`, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("query listing changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.ListingChange
+	for rows.Next() {
+		var c models.ListingChange
+		if err := rows.Scan(&c.Field, &c.Old, &c.New, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scan listing change row: %w", err)
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return changes, nil
+}
@@ -4,6 +4,8 @@ package db
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -14,10 +16,16 @@ import (
 
 // InitDB creates a pgxpool connection pool, pings the database, and returns
 // the pool. The caller is responsible for calling pool.Close() on shutdown.
+// A sqlite:// URL is rejected with a clear error: commands that call InitDB
+// directly rather than OpenStore are Postgres-only.
 func InitDB(cfg *config.Config) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if strings.HasPrefix(cfg.DatabaseURL, sqliteScheme) {
+		return nil, fmt.Errorf("db: this command needs Postgres; DATABASE_URL is a SQLite file (%s)", cfg.DatabaseURL)
+	}
+
 	poolCfg, err := pgxpool.ParseConfig(cfg.DatabaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("db: parse DATABASE_URL: %w", err)
@@ -42,3 +50,17 @@ func InitDB(cfg *config.Config) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+// CheckSchema logs a warning when migrations are pending, or returns the error
+// when cfg.RequireMigrations is set so the caller can refuse to start.
+func CheckSchema(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config) error {
+	err := CheckMigrations(ctx, pool)
+	if err == nil {
+		return nil
+	}
+	if cfg.RequireMigrations {
+		return fmt.Errorf("%w (run `go run ./cmd/migrate up`)", err)
+	}
+	log.Printf("WARNING: %v — run `go run ./cmd/migrate up`", err)
+	return nil
+}
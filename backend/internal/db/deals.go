@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// SetDealScores replaces every listing's deal score with scores in one
+// transaction: listings in scores get their expected price, score and label,
+// and every other listing's are cleared, so a listing that is no longer
+// scored (delisted, or now too rare to value) does not keep a stale one.
+func SetDealScores(ctx context.Context, pool *pgxpool.Pool, scores []models.DealScore) error {
+	ids := make([]string, len(scores))
+	expected := make([]float64, len(scores))
+	values := make([]float64, len(scores))
+	labels := make([]string, len(scores))
+	for i, s := range scores {
+		ids[i], expected[i], values[i], labels[i] = s.ListingID, s.ExpectedPrice, s.Score, s.Label
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin deal scores: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+		UPDATE listings
+		SET expected_price = NULL,
+			deal_score     = NULL,
+			deal_label     = NULL
+		WHERE deal_score IS NOT NULL
+		  AND NOT (id = ANY($1::uuid[]))`,
+		ids,
+	)
+	if err != nil {
+		return fmt.Errorf("clear deal scores: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE listings l
+		SET expected_price = s.expected,
+			deal_score     = s.score,
+			deal_label     = s.label
+		FROM unnest($1::uuid[], $2::float8[], $3::float8[], $4::text[]) AS s(id, expected, score, label)
+		WHERE l.id = s.id`,
+		ids, expected, values, labels,
+	)
+	if err != nil {
+		return fmt.Errorf("set deal scores: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit deal scores: %w", err)
+	}
+	return nil
+}
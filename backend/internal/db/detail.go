@@ -0,0 +1,102 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// Similar-listing tuning for GetListingDetail.
+const (
+	similarLimit        = 6
+	similarYearWindow   = 3     // model years either side of the listing's
+	similarMilesPerYear = 15000 // mileage gap weighed like one model year
+)
+
+// GetListingDetail assembles the detail view of one listing, active or not,
+// from store: the listing, its price history, days on market and up to
+// similarLimit similar active listings. id may be the listing's UUID or its
+// external_id; an unknown listing yields ErrListingNotFound.
+func GetListingDetail(ctx context.Context, store ListingStore, id string) (models.ListingDetail, error) {
+	l, err := store.GetListing(ctx, id)
+	if err != nil {
+		return models.ListingDetail{}, err
+	}
+	history, err := store.GetPriceHistory(ctx, l.ID)
+	if err != nil {
+		return models.ListingDetail{}, err
+	}
+	similar, err := similarListings(ctx, store, l)
+	if err != nil {
+		return models.ListingDetail{}, err
+	}
+	return models.ListingDetail{
+		Listing:      l,
+		PriceHistory: history,
+		DaysOnMarket: daysOnMarket(&l, time.Now()),
+		Similar:      similar,
+	}, nil
+}
+
+// similarListings returns the active listings of l's category, make and model
+// within similarYearWindow model years of it, closest in year and mileage
+// first. A listing without a make or model has none.
+func similarListings(ctx context.Context, store ListingStore, l models.Listing) ([]models.Listing, error) {
+	if l.Make == "" || l.Model == "" {
+		return nil, nil
+	}
+	f := ListingFilter{Category: l.Category, Make: l.Make, Model: l.Model}
+	if l.Year != nil {
+		lo, hi := *l.Year-similarYearWindow, *l.Year+similarYearWindow
+		f.YearMin, f.YearMax = &lo, &hi
+	}
+	candidates, _, err := store.GetListings(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("get similar listings: %w", err)
+	}
+
+	candidates = slices.DeleteFunc(candidates, func(c models.Listing) bool { return c.ID == l.ID })
+	slices.SortFunc(candidates, func(a, b models.Listing) int {
+		return cmp.Or(cmp.Compare(similarity(l, a), similarity(l, b)), cmp.Compare(a.ID, b.ID))
+	})
+	return candidates[:min(similarLimit, len(candidates))], nil
+}
+
+// similarity is the distance between two listings in model years, counting
+// similarMilesPerYear of mileage difference as one year. An unknown year or
+// mileage on either side counts as a full similarYearWindow apart.
+func similarity(a, b models.Listing) float64 {
+	years, miles := float64(similarYearWindow), float64(similarYearWindow)
+	if a.Year != nil && b.Year != nil {
+		years = math.Abs(float64(*a.Year - *b.Year))
+	}
+	if a.Mileage != nil && b.Mileage != nil {
+		miles = math.Abs(float64(*a.Mileage-*b.Mileage)) / similarMilesPerYear
+	}
+	return years + miles
+}
+
+// daysOnMarket measures a listing's time on the market in days, from the ad's
+// posted date (or first_seen) to delisting, now while active, or last_seen.
+func daysOnMarket(l *models.Listing, now time.Time) float64 {
+	start := l.PostedAt
+	if start == nil {
+		start = l.FirstSeen
+	}
+	if start == nil {
+		return 0
+	}
+	end := now
+	switch {
+	case l.DelistedAt != nil:
+		end = *l.DelistedAt
+	case !l.IsActive && l.LastSeen != nil:
+		end = *l.LastSeen
+	}
+	return end.Sub(*start).Hours() / 24
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// DiffListings compares the scraped fields of two versions of a listing and
+// returns one FieldChange per field that differs, in column order. Bookkeeping
+// columns (id, timestamps, is_active) are ignored. The stores diff against
+// the output of keepStoredDetails, so posted_at and ad_updated_at only show
+// up here when they moved by more than the unit the page gave them in.
+func DiffListings(prev, curr models.Listing) []models.FieldChange {
+	pairs := []struct {
+		field    string
+		old, new string
+	}{
+		{"url", prev.URL, curr.URL},
+		{"title", prev.Title, curr.Title},
+		{"make", prev.Make, curr.Make},
+		{"model", prev.Model, curr.Model},
+		{"year", intPtrStr(prev.Year), intPtrStr(curr.Year)},
+		{"mileage", intPtrStr(prev.Mileage), intPtrStr(curr.Mileage)},
+		{"price", priceStr(prev.Price), priceStr(curr.Price)},
+		{"currency", prev.Currency, curr.Currency},
+		{"condition", prev.Condition, curr.Condition},
+		{"transmission", prev.Transmission, curr.Transmission},
+		{"fuel_type", prev.FuelType, curr.FuelType},
+		{"color", prev.Color, curr.Color},
+		{"body_type", prev.BodyType, curr.BodyType},
+		{"drive", prev.Drive, curr.Drive},
+		{"cylinders", prev.Cylinders, curr.Cylinders},
+		{"steering", prev.Steering, curr.Steering},
+		{"interior_color", prev.InteriorColor, curr.InteriorColor},
+		{"doors", prev.Doors, curr.Doors},
+		{"on_island", boolPtrStr(prev.OnIsland), boolPtrStr(curr.OnIsland)},
+		{"attributes", attributesStr(prev.Attributes), attributesStr(curr.Attributes)},
+		{"images", strings.Join(prev.Images, "\n"), strings.Join(curr.Images, "\n")},
+		{"location", prev.Location, curr.Location},
+		{"description", prev.Description, curr.Description},
+		{"seller_name", prev.SellerName, curr.SellerName},
+		{"seller_url", prev.SellerURL, curr.SellerURL},
+		{"posted_at", timePtrStr(prev.PostedAt), timePtrStr(curr.PostedAt)},
+		{"ad_updated_at", timePtrStr(prev.AdUpdatedAt), timePtrStr(curr.AdUpdatedAt)},
+	}
+
+	var changes []models.FieldChange
+	for _, p := range pairs {
+		if p.old != p.new {
+			changes = append(changes, models.FieldChange{Field: p.field, Old: p.old, New: p.new})
+		}
+	}
+	return changes
+}
+
+func intPtrStr(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func timePtrStr(v *time.Time) string {
+	if v == nil {
+		return ""
+	}
+	return v.UTC().Format(time.RFC3339)
+}
+
+func boolPtrStr(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
+
+// attributesStr renders an attribute map as "key=value" pairs sorted by key.
+func attributesStr(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func priceStr(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
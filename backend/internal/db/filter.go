@@ -0,0 +1,229 @@
+package db
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// Sort keys accepted by ListingFilter.Sort.
+const (
+	SortPrice     = "price"
+	SortYear      = "year"
+	SortMileage   = "mileage"
+	SortFirstSeen = "first_seen"
+	SortDealScore = "deal_score"
+)
+
+// sortColumns maps each sort key to its listings column.
+var sortColumns = map[string]string{
+	SortPrice:     "price",
+	SortYear:      "year",
+	SortMileage:   "mileage",
+	SortFirstSeen: "first_seen",
+	SortDealScore: "deal_score",
+}
+
+// ValidSort reports whether key is a ListingFilter sort key.
+func ValidSort(key string) bool {
+	_, ok := sortColumns[key]
+	return ok
+}
+
+// ListingFilter narrows, orders and pages GetListings. Zero-value fields match
+// everything. Text fields match case-insensitively, exactly except Location,
+// which matches any part of the location. Ranges are inclusive, and listings
+// with no value for a ranged field never match that range.
+type ListingFilter struct {
+	Category     string // listings.category slug
+	SellerType   string // models.SellerTypeDealer or models.SellerTypePrivate
+	Make         string
+	Model        string
+	BodyType     string
+	Transmission string
+	FuelType     string
+	Location     string
+	OnIsland     *bool
+
+	YearMin, YearMax       *int
+	PriceMin, PriceMax     *float64
+	MileageMin, MileageMax *int
+
+	// Sort is one of the Sort* keys; empty means newest first. Listings with
+	// no value for the sort field come last either way.
+	Sort string
+	Desc bool
+
+	// Limit caps the page size; 0 returns every match from Offset on.
+	Limit  int
+	Offset int
+}
+
+// where renders f's conditions as a SQL WHERE clause over unaliased listings,
+// numbering parameters with placeholder (e.g. "$%d" or "?%d").
+func (f ListingFilter) where(placeholder string) (string, []any) {
+	conds := []string{"is_active = TRUE"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf(placeholder, len(args))
+	}
+
+	if f.Category != "" {
+		conds = append(conds, "category = "+arg(f.Category))
+	}
+	if f.SellerType != "" {
+		conds = append(conds, "seller_url IN (SELECT profile_url FROM sellers WHERE seller_type = "+arg(f.SellerType)+")")
+	}
+	for _, eq := range []struct{ column, value string }{
+		{"make", f.Make},
+		{"model", f.Model},
+		{"body_type", f.BodyType},
+		{"transmission", f.Transmission},
+		{"fuel_type", f.FuelType},
+	} {
+		if eq.value != "" {
+			conds = append(conds, "LOWER("+eq.column+") = LOWER("+arg(eq.value)+")")
+		}
+	}
+	if f.Location != "" {
+		conds = append(conds, `LOWER(location) LIKE `+arg("%"+escapeLike(strings.ToLower(f.Location))+"%")+` ESCAPE '\'`)
+	}
+	if f.OnIsland != nil {
+		conds = append(conds, "on_island = "+arg(*f.OnIsland))
+	}
+	if f.YearMin != nil {
+		conds = append(conds, "year >= "+arg(*f.YearMin))
+	}
+	if f.YearMax != nil {
+		conds = append(conds, "year <= "+arg(*f.YearMax))
+	}
+	if f.PriceMin != nil {
+		conds = append(conds, "price >= "+arg(*f.PriceMin))
+	}
+	if f.PriceMax != nil {
+		conds = append(conds, "price <= "+arg(*f.PriceMax))
+	}
+	if f.MileageMin != nil {
+		conds = append(conds, "mileage >= "+arg(*f.MileageMin))
+	}
+	if f.MileageMax != nil {
+		conds = append(conds, "mileage <= "+arg(*f.MileageMax))
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderBy renders f's sort as an ORDER BY clause. id breaks ties so pages
+// don't overlap.
+func (f ListingFilter) orderBy() string {
+	column, ok := sortColumns[f.Sort]
+	if !ok {
+		return "ORDER BY created_at DESC, id"
+	}
+	dir := "ASC"
+	if f.Desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, id", column, dir)
+}
+
+// page renders f's LIMIT/OFFSET clause. SQLite needs a LIMIT before OFFSET,
+// so "no limit" is LIMIT -1 there and LIMIT ALL in Postgres.
+func (f ListingFilter) page(noLimit string) string {
+	limit := noLimit
+	if f.Limit > 0 {
+		limit = fmt.Sprint(f.Limit)
+	}
+	return fmt.Sprintf("LIMIT %s OFFSET %d", limit, max(f.Offset, 0))
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// matches reports whether the active listing l passes f's conditions, the
+// way where does in SQL. l.SellerType must already be resolved.
+func (f ListingFilter) matches(l models.Listing) bool {
+	switch {
+	case f.Category != "" && l.Category != f.Category,
+		f.SellerType != "" && l.SellerType != f.SellerType,
+		f.Make != "" && !strings.EqualFold(l.Make, f.Make),
+		f.Model != "" && !strings.EqualFold(l.Model, f.Model),
+		f.BodyType != "" && !strings.EqualFold(l.BodyType, f.BodyType),
+		f.Transmission != "" && !strings.EqualFold(l.Transmission, f.Transmission),
+		f.FuelType != "" && !strings.EqualFold(l.FuelType, f.FuelType),
+		f.Location != "" && !strings.Contains(strings.ToLower(l.Location), strings.ToLower(f.Location)),
+		f.OnIsland != nil && (l.OnIsland == nil || *l.OnIsland != *f.OnIsland):
+		return false
+	}
+	return inRange(l.Year, f.YearMin, f.YearMax) &&
+		inRange(&l.Price, f.PriceMin, f.PriceMax) &&
+		inRange(l.Mileage, f.MileageMin, f.MileageMax)
+}
+
+// inRange reports whether v lies within the optional bounds; a nil v only
+// passes when there are no bounds.
+func inRange[T cmp.Ordered](v, lo, hi *T) bool {
+	if lo == nil && hi == nil {
+		return true
+	}
+	if v == nil {
+		return false
+	}
+	return (lo == nil || *v >= *lo) && (hi == nil || *v <= *hi)
+}
+
+// compare orders two listings the way orderBy does in SQL.
+func (f ListingFilter) compare(a, b models.Listing) int {
+	var c int
+	switch f.Sort {
+	case SortPrice:
+		c = cmp.Compare(a.Price, b.Price)
+	case SortYear:
+		c = compareNullable(a.Year, b.Year, f.Desc)
+	case SortMileage:
+		c = compareNullable(a.Mileage, b.Mileage, f.Desc)
+	case SortFirstSeen:
+		c = compareNullable(unixPtr(a.FirstSeen), unixPtr(b.FirstSeen), f.Desc)
+	case SortDealScore:
+		c = compareNullable(a.DealScore, b.DealScore, f.Desc)
+	default:
+		c = -compareNullable(unixPtr(a.CreatedAt), unixPtr(b.CreatedAt), true)
+		return cmp.Or(c, cmp.Compare(a.ID, b.ID))
+	}
+	if f.Desc {
+		c = -c
+	}
+	return cmp.Or(c, cmp.Compare(a.ID, b.ID))
+}
+
+// compareNullable compares optional values so that nil sorts last once the
+// caller has applied desc.
+func compareNullable[T cmp.Ordered](a, b *T, desc bool) int {
+	last := 1
+	if desc {
+		last = -1
+	}
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return last
+	case b == nil:
+		return -last
+	}
+	return cmp.Compare(*a, *b)
+}
+
+// unixPtr returns t in Unix nanoseconds, or nil for a nil t.
+func unixPtr(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	n := t.UnixNano()
+	return &n
+}
@@ -0,0 +1,492 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// MemoryStore is an in-memory ListingStore with the same semantics as the
+// Postgres one, for handler tests and local experiments. It is safe for
+// concurrent use. The zero value is not usable; call NewMemoryStore.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	now      func() time.Time
+	listings map[string]*models.Listing        // keyed by external_id
+	changes  map[string][]models.ListingChange // keyed by listing ID
+	prices   map[string][]models.PricePoint    // keyed by listing ID
+	sellers  map[string]*models.Seller         // keyed by profile URL
+	runs     []models.ScrapeRun
+}
+
+var _ ListingStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:      time.Now,
+		listings: make(map[string]*models.Listing),
+		changes:  make(map[string][]models.ListingChange),
+		prices:   make(map[string][]models.PricePoint),
+		sellers:  make(map[string]*models.Seller),
+	}
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *MemoryStore) UpsertListings(ctx context.Context, listings []models.Listing) ([]UpsertResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	results := make([]UpsertResult, len(listings))
+	for i, l := range listings {
+		l = cloneListing(l)
+		l.Category = categoryOrDefault(l.Category)
+		l.SellerType, l.SellerMarkers = "", nil
+		l.IsActive, l.LastSeen, l.DelistedAt, l.UpdatedAt = true, &now, nil, &now
+
+		prev, ok := s.listings[l.ExternalID]
+		if !ok {
+			l.ID = newUUID()
+			l.FirstSeen, l.CreatedAt = &now, &now
+			results[i].Inserted = true
+			if l.Price > 0 {
+				s.prices[l.ID] = append(s.prices[l.ID], models.PricePoint{Price: l.Price, RecordedAt: now})
+			}
+			s.listings[l.ExternalID] = &l
+			continue
+		}
+
+		l = keepStoredDetails(l, *prev)
+		l.ID, l.FirstSeen, l.CreatedAt = prev.ID, prev.FirstSeen, prev.CreatedAt
+		l.ExpectedPrice, l.DealScore, l.DealLabel = prev.ExpectedPrice, prev.DealScore, prev.DealLabel
+		results[i].Changes = DiffListings(*prev, l)
+		results[i].PriceChanged = prev.Price != l.Price && l.Price > 0
+		if results[i].PriceChanged {
+			s.prices[l.ID] = append(s.prices[l.ID], models.PricePoint{Price: l.Price, RecordedAt: now})
+		}
+		for _, c := range results[i].Changes {
+			s.changes[l.ID] = append(s.changes[l.ID], models.ListingChange{FieldChange: c, ChangedAt: now})
+		}
+		s.listings[l.ExternalID] = &l
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) GetListings(ctx context.Context, f ListingFilter) ([]models.Listing, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var listings []models.Listing
+	for _, l := range s.listings {
+		if !l.IsActive {
+			continue
+		}
+		if out := s.readListing(l); f.matches(out) {
+			listings = append(listings, out)
+		}
+	}
+	slices.SortFunc(listings, f.compare)
+
+	total := len(listings)
+	listings = listings[min(max(f.Offset, 0), total):]
+	if f.Limit > 0 {
+		listings = listings[:min(f.Limit, len(listings))]
+	}
+	return listings, total, nil
+}
+
+func (s *MemoryStore) GetListing(ctx context.Context, id string) (models.Listing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.lookup(id)
+	if l == nil {
+		return models.Listing{}, ErrListingNotFound
+	}
+	return s.readListing(l), nil
+}
+
+func (s *MemoryStore) GetStats(ctx context.Context, category string) (models.Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var active []*models.Listing
+	for _, l := range s.listings {
+		if l.IsActive && (category == "" || l.Category == category) {
+			active = append(active, l)
+		}
+	}
+	return statsFromListings(active, s.now()), nil
+}
+
+func (s *MemoryStore) GetListingChanges(ctx context.Context, id string) ([]models.ListingChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.lookup(id)
+	if l == nil {
+		return nil, ErrListingNotFound
+	}
+	changes := slices.Clone(s.changes[l.ID])
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].ChangedAt.Equal(changes[j].ChangedAt) {
+			return changes[i].ChangedAt.After(changes[j].ChangedAt)
+		}
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+func (s *MemoryStore) GetPriceHistory(ctx context.Context, id string) ([]models.PricePoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.lookup(id)
+	if l == nil {
+		return nil, ErrListingNotFound
+	}
+	return slices.Clone(s.prices[l.ID]), nil
+}
+
+// GetTrends computes the package-level GetTrends figures directly from the
+// stored listings and price history; MemoryStore has no daily_inventory.
+func (s *MemoryStore) GetTrends(ctx context.Context, f TrendFilter) ([]models.TrendBucket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := newTrendSet(f.Granularity)
+	today := bucketStart(s.now(), GranularityDay)
+	for _, l := range s.listings {
+		if (f.Category != "" && l.Category != f.Category) ||
+			(f.Make != "" && !strings.EqualFold(l.Make, f.Make)) ||
+			(f.BodyType != "" && !strings.EqualFold(l.BodyType, f.BodyType)) {
+			continue
+		}
+		if l.DelistedAt != nil {
+			set.at(*l.DelistedAt).Delisted++
+		}
+		if l.FirstSeen == nil {
+			continue
+		}
+		set.at(*l.FirstSeen).New++
+
+		last := today
+		switch {
+		case l.DelistedAt != nil:
+			last = bucketStart(*l.DelistedAt, GranularityDay)
+		case !l.IsActive && l.LastSeen != nil:
+			last = bucketStart(*l.LastSeen, GranularityDay)
+		}
+		for day := bucketStart(*l.FirstSeen, GranularityDay); !day.After(last); day = day.AddDate(0, 0, 1) {
+			set.addLive(day, s.priceAt(l, day.AddDate(0, 0, 1)))
+		}
+	}
+	return set.list(), nil
+}
+
+// priceAt returns l's last recorded price before end, or its current price
+// when none was. Callers hold s.mu.
+func (s *MemoryStore) priceAt(l *models.Listing, end time.Time) float64 {
+	price := l.Price
+	for _, p := range s.prices[l.ID] {
+		if p.RecordedAt.Before(end) {
+			price = p.Price
+		}
+	}
+	return price
+}
+
+// UpsertSellers records sellers like the package-level UpsertSellers: blank
+// names never replace stored ones and profile markers are merged.
+func (s *MemoryStore) UpsertSellers(ctx context.Context, sellers []models.Seller) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, in := range sellers {
+		stored, ok := s.sellers[in.ProfileURL]
+		if !ok {
+			stored = &models.Seller{
+				ID:         newUUID(),
+				ProfileURL: in.ProfileURL,
+				SellerType: models.SellerTypePrivate,
+				FirstSeen:  &now,
+			}
+			s.sellers[in.ProfileURL] = stored
+		}
+		if in.Name != "" {
+			stored.Name = in.Name
+		}
+		for _, m := range in.ProfileMarkers {
+			if !slices.Contains(stored.ProfileMarkers, m) {
+				stored.ProfileMarkers = append(stored.ProfileMarkers, m)
+			}
+		}
+		stored.LastSeen = &now
+	}
+	return nil
+}
+
+// SetSellerType stores the classifier's verdict for one seller.
+func (s *MemoryStore) SetSellerType(ctx context.Context, profileURL, sellerType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.sellers[profileURL]; ok {
+		stored.SellerType = sellerType
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetSellers(ctx context.Context, sellerType string) ([]models.Seller, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	var sellers []models.Seller
+	for _, stored := range s.sellers {
+		if sellerType != "" && stored.SellerType != sellerType {
+			continue
+		}
+		out := *stored
+		out.ProfileMarkers = slices.Clone(stored.ProfileMarkers)
+
+		var activePrice, days float64
+		for _, l := range s.listings {
+			if l.SellerURL != out.ProfileURL {
+				continue
+			}
+			out.ListingCount++
+			if l.IsActive {
+				out.InventoryCount++
+				activePrice += l.Price
+			}
+			days += daysOnMarket(l, now)
+		}
+		if out.InventoryCount > 0 {
+			out.AvgPrice = activePrice / float64(out.InventoryCount)
+		}
+		if out.ListingCount > 0 {
+			out.AvgDaysOnMarket = days / float64(out.ListingCount)
+		}
+		sellers = append(sellers, out)
+	}
+	sort.Slice(sellers, func(i, j int) bool {
+		if sellers[i].InventoryCount != sellers[j].InventoryCount {
+			return sellers[i].InventoryCount > sellers[j].InventoryCount
+		}
+		return sellers[i].Name < sellers[j].Name
+	})
+	return sellers, nil
+}
+
+// SetDealScores replaces every listing's deal score like the package-level
+// SetDealScores.
+func (s *MemoryStore) SetDealScores(ctx context.Context, scores []models.DealScore) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byID := make(map[string]models.DealScore, len(scores))
+	for _, sc := range scores {
+		byID[sc.ListingID] = sc
+	}
+	for _, l := range s.listings {
+		sc, ok := byID[l.ID]
+		if !ok {
+			l.ExpectedPrice, l.DealScore, l.DealLabel = nil, nil, ""
+			continue
+		}
+		l.ExpectedPrice, l.DealScore, l.DealLabel = &sc.ExpectedPrice, &sc.Score, sc.Label
+	}
+	return nil
+}
+
+// StartScrapeRun records a run in the "running" state and returns its ID.
+func (s *MemoryStore) StartScrapeRun(ctx context.Context, run models.ScrapeRun) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.ID = newUUID()
+	s.runs = append(s.runs, run)
+	return run.ID, nil
+}
+
+// FinishScrapeRun replaces the run with the given ID, keeping its start time.
+func (s *MemoryStore) FinishScrapeRun(ctx context.Context, id string, run models.ScrapeRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.runs {
+		if s.runs[i].ID == id {
+			run.ID, run.StartedAt = id, s.runs[i].StartedAt
+			s.runs[i] = run
+			return nil
+		}
+	}
+	return fmt.Errorf("finish scrape run %s: no such run", id)
+}
+
+func (s *MemoryStore) GetScrapeRuns(ctx context.Context, limit int) ([]models.ScrapeRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := slices.Clone(s.runs)
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs[:min(limit, len(runs))], nil
+}
+
+// lookup finds a listing by UUID or external_id. Callers hold s.mu.
+func (s *MemoryStore) lookup(id string) *models.Listing {
+	if l, ok := s.listings[id]; ok {
+		return l
+	}
+	for _, l := range s.listings {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// readListing copies a stored listing and fills in its seller's type, as
+// listingColumns does. Callers hold s.mu.
+func (s *MemoryStore) readListing(l *models.Listing) models.Listing {
+	out := cloneListing(*l)
+	if seller, ok := s.sellers[l.SellerURL]; ok && l.SellerURL != "" {
+		out.SellerType = seller.SellerType
+	}
+	return out
+}
+
+// cloneListing copies l so the store never shares slices or maps with callers.
+func cloneListing(l models.Listing) models.Listing {
+	l.Images = slices.Clone(l.Images)
+	l.Attributes = maps.Clone(l.Attributes)
+	return l
+}
+
+// statsFromListings computes models.Stats over the given active listings the
+// way GetStats does in SQL.
+func statsFromListings(listings []*models.Listing, now time.Time) models.Stats {
+	stats := models.Stats{
+		TopBrands:        make([]models.BrandStat, 0),
+		BodyTypes:        make([]models.BodyTypeStat, 0),
+		YearDistribution: make([]models.YearStat, 0),
+	}
+	if len(listings) == 0 {
+		return stats
+	}
+
+	var (
+		prices     []float64
+		priceSum   float64
+		mileageSum float64
+		mileageN   int
+		weekAgo    = now.Add(-7 * 24 * time.Hour)
+		brands     = make(map[string]*models.BrandStat)
+		bodyTypes  = make(map[string]*models.BodyTypeStat)
+		years      = make(map[int]int)
+	)
+	for _, l := range listings {
+		prices = append(prices, l.Price)
+		priceSum += l.Price
+		if l.FirstSeen != nil && !l.FirstSeen.Before(weekAgo) {
+			stats.NewThisWeek++
+		}
+		if l.Mileage != nil {
+			mileageSum += float64(*l.Mileage)
+			mileageN++
+		}
+		if l.Make != "" {
+			b, ok := brands[l.Make]
+			if !ok {
+				b = &models.BrandStat{Name: l.Make}
+				brands[l.Make] = b
+			}
+			b.Count++
+			b.AvgPrice += l.Price
+		}
+		bt := strings.TrimSpace(l.BodyType)
+		if bt == "" {
+			bt = "Other"
+		}
+		t, ok := bodyTypes[bt]
+		if !ok {
+			t = &models.BodyTypeStat{Type: bt}
+			bodyTypes[bt] = t
+		}
+		t.Count++
+		t.AvgPrice += l.Price
+		if l.Year != nil {
+			years[*l.Year]++
+		}
+	}
+
+	stats.TotalListings = len(listings)
+	stats.AvgPrice = priceSum / float64(len(listings))
+	stats.MedianPrice = median(prices)
+	if mileageN > 0 {
+		stats.AvgMileage = mileageSum / float64(mileageN)
+	}
+
+	for _, b := range brands {
+		b.AvgPrice /= float64(b.Count)
+		stats.TopBrands = append(stats.TopBrands, *b)
+	}
+	slices.SortFunc(stats.TopBrands, func(a, b models.BrandStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	stats.TopBrands = stats.TopBrands[:min(8, len(stats.TopBrands))]
+
+	for _, t := range bodyTypes {
+		t.AvgPrice /= float64(t.Count)
+		stats.BodyTypes = append(stats.BodyTypes, *t)
+	}
+	slices.SortFunc(stats.BodyTypes, func(a, b models.BodyTypeStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Type, b.Type))
+	})
+
+	for y, n := range years {
+		stats.YearDistribution = append(stats.YearDistribution, models.YearStat{Year: y, Count: n})
+	}
+	slices.SortFunc(stats.YearDistribution, func(a, b models.YearStat) int { return cmp.Compare(a.Year, b.Year) })
+
+	return stats
+}
+
+// median returns the interpolated median of vals like PERCENTILE_CONT(0.5),
+// or 0 when vals is empty. vals is sorted in place.
+func median(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	slices.Sort(vals)
+	mid := len(vals) / 2
+	if len(vals)%2 == 1 {
+		return vals[mid]
+	}
+	return (vals[mid-1] + vals[mid]) / 2
+}
+
+// newUUID returns a random (version 4) UUID string, the same shape as the
+// gen_random_uuid() IDs Postgres assigns.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationFiles holds the Postgres migrations in migrations/ and the SQLite
+// ones (see OpenSQLite) in migrations/sqlite/.
+//
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationNameRe matches migration file names: a version number, an
+// underscore and a snake_case description, e.g. 0002_listing_scores.sql.
+var migrationNameRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// migrationLockKey is the advisory lock taken while a migration is applied,
+// so two processes running migrations at once apply each one only once.
+const migrationLockKey = 0x65636179 // "ecay"
+
+// ErrSchemaBehind is returned by CheckMigrations when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migration is one embedded schema migration.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationState is a migration together with when it was applied. Known is
+// false for versions recorded in the database but not embedded in this
+// binary, which usually means the binary is older than the database.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Known     bool
+}
+
+// Migrations returns the embedded Postgres migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	return readMigrations("migrations")
+}
+
+// readMigrations returns the migrations in one embedded directory ordered by
+// version. Subdirectories are skipped.
+func readMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationNameRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_description.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", prev, e.Name(), version)
+		}
+		seen[version] = e.Name()
+
+		body, err := migrationFiles.ReadFile(dir + "/" + e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: m[2], SQL: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies every pending migration in version order and returns the
+// ones it applied. Each migration runs in its own transaction together with
+// its schema_migrations row, so a failed migration leaves the database at the
+// previous version.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		ok, err := applyMigration(ctx, pool, m)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// applyMigration applies m unless it is already recorded, reporting whether
+// it ran.
+func applyMigration(ctx context.Context, pool *pgxpool.Pool, m Migration) (bool, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin migration %d: %w", m.Version, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockKey); err != nil {
+		return false, fmt.Errorf("lock migrations: %w", err)
+	}
+
+	var done bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version,
+	).Scan(&done)
+	if err != nil {
+		return false, fmt.Errorf("check migration %d: %w", m.Version, err)
+	}
+	if done {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, m.SQL); err != nil {
+		return false, fmt.Errorf("apply migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name,
+	)
+	if err != nil {
+		return false, fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit migration %d: %w", m.Version, err)
+	}
+	return true, nil
+}
+
+// MigrationStatus returns every embedded migration with its applied time (nil
+// when pending), followed by any applied versions this binary doesn't know.
+func MigrationStatus(ctx context.Context, pool *pgxpool.Pool) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationState{Version: m.Version, Name: m.Name, Known: true}
+		if a, ok := applied[m.Version]; ok {
+			s.AppliedAt = a.AppliedAt
+			delete(applied, m.Version)
+		}
+		states = append(states, s)
+	}
+
+	var unknown []MigrationState
+	for _, a := range applied {
+		unknown = append(unknown, a)
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+	return append(states, unknown...), nil
+}
+
+// appliedMigrations reads schema_migrations keyed by version. A database that
+// has never been migrated has no table and no applied versions.
+func appliedMigrations(ctx context.Context, pool *pgxpool.Pool) (map[int]MigrationState, error) {
+	var exists bool
+	err := pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	applied := make(map[int]MigrationState)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := pool.Query(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s MigrationState
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations row: %w", err)
+		}
+		applied[s.Version] = s
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return applied, nil
+}
+
+// CheckMigrations returns an error wrapping ErrSchemaBehind when any embedded
+// migration has not been applied.
+func CheckMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	states, err := MigrationStatus(ctx, pool)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, s := range states {
+		if s.Known && s.AppliedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d pending migration(s)", ErrSchemaBehind, pending)
+	}
+	return nil
+}
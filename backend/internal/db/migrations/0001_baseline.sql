@@ -0,0 +1,138 @@
+-- Baseline: the schema as it stood before versioned migrations. Every
+-- statement is idempotent so databases created from the old schema.sql can
+-- apply it safely and be recorded as migrated.
+
+CREATE TABLE IF NOT EXISTS listings (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  external_id    TEXT UNIQUE NOT NULL,
+  category       TEXT NOT NULL DEFAULT 'autos',
+  url            TEXT NOT NULL,
+  title          TEXT NOT NULL,
+  make           TEXT,
+  model          TEXT,
+  year           INTEGER,
+  mileage        INTEGER,
+  price          NUMERIC(10, 2),
+  currency       TEXT DEFAULT 'KYD',
+  condition      TEXT,
+  transmission   TEXT,
+  fuel_type      TEXT,
+  color          TEXT,
+  body_type      TEXT,
+  drive          TEXT,
+  cylinders      TEXT,
+  steering       TEXT,
+  interior_color TEXT,
+  doors          TEXT,
+  on_island      BOOLEAN,
+  attributes     JSONB,
+  description    TEXT,
+  images         TEXT[],
+  location       TEXT,
+  seller_name    TEXT,
+  seller_url     TEXT,
+  posted_at      TIMESTAMPTZ,
+  ad_updated_at  TIMESTAMPTZ,
+  is_active      BOOLEAN DEFAULT TRUE,
+  first_seen     TIMESTAMPTZ DEFAULT NOW(),
+  last_seen      TIMESTAMPTZ DEFAULT NOW(),
+  delisted_at    TIMESTAMPTZ,
+  created_at     TIMESTAMPTZ DEFAULT NOW(),
+  updated_at     TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS price_history (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  listing_id  UUID REFERENCES listings(id) ON DELETE CASCADE,
+  price       NUMERIC(10, 2) NOT NULL,
+  recorded_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS scrape_runs (
+  id                       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  started_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  finished_at              TIMESTAMPTZ,
+  status                   TEXT NOT NULL DEFAULT 'running',
+  complete                 BOOLEAN NOT NULL DEFAULT FALSE,
+  pages_visited            INTEGER NOT NULL DEFAULT 0,
+  raw_cards                INTEGER NOT NULL DEFAULT 0,
+  accepted                 INTEGER NOT NULL DEFAULT 0,
+  unchanged                INTEGER NOT NULL DEFAULT 0,
+  skipped_empty            INTEGER NOT NULL DEFAULT 0,
+  skipped_no_year          INTEGER NOT NULL DEFAULT 0,
+  skipped_low_price        INTEGER NOT NULL DEFAULT 0,
+  skipped_price_on_request INTEGER NOT NULL DEFAULT 0,
+  detail_failures          INTEGER NOT NULL DEFAULT 0,
+  blocked_pages            INTEGER NOT NULL DEFAULT 0,
+  enrich_calls             INTEGER NOT NULL DEFAULT 0,
+  enrich_failures          INTEGER NOT NULL DEFAULT 0,
+  inserted                 INTEGER NOT NULL DEFAULT 0,
+  updated                  INTEGER NOT NULL DEFAULT 0,
+  price_changed            INTEGER NOT NULL DEFAULT 0,
+  upsert_errors            INTEGER NOT NULL DEFAULT 0,
+  delisted                 INTEGER NOT NULL DEFAULT 0,
+  error                    TEXT
+);
+
+-- Raw inputs the parser saw for each advert card per scrape run, kept so
+-- history can be re-parsed after parser fixes. detail_html_gz is gzip.
+CREATE TABLE IF NOT EXISTS listing_snapshots (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  run_id         UUID REFERENCES scrape_runs(id) ON DELETE SET NULL,
+  external_id    TEXT NOT NULL,
+  category       TEXT NOT NULL DEFAULT 'autos',
+  url            TEXT NOT NULL,
+  card_text      TEXT NOT NULL,
+  img_src        TEXT,
+  detail_html_gz BYTEA,
+  detail_text    TEXT,
+  detail_fields  JSONB,
+  detail_images  TEXT[],
+  captured_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Every scraped field that changed between two runs (mileage edits, re-titles,
+-- image swaps, …). Values are display strings; NULL means the field was unset.
+CREATE TABLE IF NOT EXISTS listing_changes (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  listing_id  UUID REFERENCES listings(id) ON DELETE CASCADE,
+  field       TEXT NOT NULL,
+  old_value   TEXT,
+  new_value   TEXT,
+  changed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per ecaytrade seller profile. seller_type is set by the scraper's
+-- dealer/private classifier after each run.
+CREATE TABLE IF NOT EXISTS sellers (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_url     TEXT UNIQUE NOT NULL,
+  name            TEXT,
+  seller_type     TEXT NOT NULL DEFAULT 'private',
+  profile_markers TEXT[] NOT NULL DEFAULT '{}',
+  first_seen      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_seen       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index to speed up listing lookups
+CREATE INDEX IF NOT EXISTS idx_listings_external_id ON listings(external_id);
+CREATE INDEX IF NOT EXISTS idx_listings_is_active   ON listings(is_active);
+CREATE INDEX IF NOT EXISTS idx_listings_make        ON listings(make);
+CREATE INDEX IF NOT EXISTS idx_listings_created_at  ON listings(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_scrape_runs_started  ON scrape_runs(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_snapshots_external   ON listing_snapshots(external_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_listing_changes      ON listing_changes(listing_id, changed_at DESC);
+
+-- Columns added after the initial release. Safe to re-run on existing databases.
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS delisted_at TIMESTAMPTZ;
+ALTER TABLE scrape_runs ADD COLUMN IF NOT EXISTS unchanged INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scrape_runs ADD COLUMN IF NOT EXISTS blocked_pages INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'autos';
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS attributes JSONB;
+ALTER TABLE listing_snapshots ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'autos';
+ALTER TABLE listing_snapshots ADD COLUMN IF NOT EXISTS detail_images TEXT[];
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS seller_url TEXT;
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS posted_at TIMESTAMPTZ;
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS ad_updated_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_listings_category ON listings(category);
+CREATE INDEX IF NOT EXISTS idx_listings_seller_url ON listings(seller_url);
@@ -0,0 +1,9 @@
+-- Deal scores: the valuation model's expected price for each active listing
+-- and how its asking price compares, written by cmd/scraper after every run
+-- (see SetDealScores). NULL when a listing could not be scored.
+
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS expected_price NUMERIC(10, 2);
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS deal_score     DOUBLE PRECISION;
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS deal_label     TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_listings_deal_score ON listings(deal_score DESC);
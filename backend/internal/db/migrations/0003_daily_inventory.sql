@@ -0,0 +1,17 @@
+-- Daily inventory: one row per listing live on each UTC day, with its price
+-- at the end of that day. Rebuilt by cmd/scraper after every run (see
+-- MaterializeDailyInventory) so /api/trends aggregates this table instead of
+-- replaying listing and price history on every request.
+
+CREATE TABLE IF NOT EXISTS daily_inventory (
+  day        DATE NOT NULL,
+  listing_id UUID NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+  category   TEXT NOT NULL,
+  make       TEXT,
+  body_type  TEXT,
+  price      NUMERIC(10, 2),
+  PRIMARY KEY (day, listing_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_listings_first_seen  ON listings(first_seen);
+CREATE INDEX IF NOT EXISTS idx_listings_delisted_at ON listings(delisted_at);
@@ -0,0 +1,6 @@
+-- Card title: the listing's title as parsed from its listings-page card,
+-- before AI enrichment rewrites listings.title. Incremental scrapes compare
+-- fresh cards against it (see GetKnownListings); NULL for rows written
+-- before this column existed, which fall back to title.
+
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS card_title TEXT;
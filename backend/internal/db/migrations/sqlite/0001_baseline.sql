@@ -0,0 +1,120 @@
+-- SQLite version of the schema, for local development (see OpenSQLite). It
+-- mirrors the Postgres migrations with SQLite types: UUIDs are generated by
+-- the store, timestamps are UTC text in sqliteTimeLayout, booleans are 0/1
+-- and arrays/JSONB columns hold JSON text.
+
+CREATE TABLE IF NOT EXISTS listings (
+  id             TEXT PRIMARY KEY,
+  external_id    TEXT UNIQUE NOT NULL,
+  category       TEXT NOT NULL DEFAULT 'autos',
+  url            TEXT NOT NULL,
+  title          TEXT NOT NULL,
+  make           TEXT,
+  model          TEXT,
+  year           INTEGER,
+  mileage        INTEGER,
+  price          REAL,
+  currency       TEXT DEFAULT 'KYD',
+  condition      TEXT,
+  transmission   TEXT,
+  fuel_type      TEXT,
+  color          TEXT,
+  body_type      TEXT,
+  drive          TEXT,
+  cylinders      TEXT,
+  steering       TEXT,
+  interior_color TEXT,
+  doors          TEXT,
+  on_island      INTEGER,
+  attributes     TEXT,
+  description    TEXT,
+  images         TEXT,
+  location       TEXT,
+  seller_name    TEXT,
+  seller_url     TEXT,
+  posted_at      TEXT,
+  ad_updated_at  TEXT,
+  is_active      INTEGER NOT NULL DEFAULT 1,
+  first_seen     TEXT,
+  last_seen      TEXT,
+  delisted_at    TEXT,
+  created_at     TEXT,
+  updated_at     TEXT
+);
+
+CREATE TABLE IF NOT EXISTS price_history (
+  id          TEXT PRIMARY KEY,
+  listing_id  TEXT REFERENCES listings(id) ON DELETE CASCADE,
+  price       REAL NOT NULL,
+  recorded_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scrape_runs (
+  id                       TEXT PRIMARY KEY,
+  started_at               TEXT NOT NULL,
+  finished_at              TEXT,
+  status                   TEXT NOT NULL DEFAULT 'running',
+  complete                 INTEGER NOT NULL DEFAULT 0,
+  pages_visited            INTEGER NOT NULL DEFAULT 0,
+  raw_cards                INTEGER NOT NULL DEFAULT 0,
+  accepted                 INTEGER NOT NULL DEFAULT 0,
+  unchanged                INTEGER NOT NULL DEFAULT 0,
+  skipped_empty            INTEGER NOT NULL DEFAULT 0,
+  skipped_no_year          INTEGER NOT NULL DEFAULT 0,
+  skipped_low_price        INTEGER NOT NULL DEFAULT 0,
+  skipped_price_on_request INTEGER NOT NULL DEFAULT 0,
+  detail_failures          INTEGER NOT NULL DEFAULT 0,
+  blocked_pages            INTEGER NOT NULL DEFAULT 0,
+  enrich_calls             INTEGER NOT NULL DEFAULT 0,
+  enrich_failures          INTEGER NOT NULL DEFAULT 0,
+  inserted                 INTEGER NOT NULL DEFAULT 0,
+  updated                  INTEGER NOT NULL DEFAULT 0,
+  price_changed            INTEGER NOT NULL DEFAULT 0,
+  upsert_errors            INTEGER NOT NULL DEFAULT 0,
+  delisted                 INTEGER NOT NULL DEFAULT 0,
+  error                    TEXT
+);
+
+CREATE TABLE IF NOT EXISTS listing_snapshots (
+  id             TEXT PRIMARY KEY,
+  run_id         TEXT REFERENCES scrape_runs(id) ON DELETE SET NULL,
+  external_id    TEXT NOT NULL,
+  category       TEXT NOT NULL DEFAULT 'autos',
+  url            TEXT NOT NULL,
+  card_text      TEXT NOT NULL,
+  img_src        TEXT,
+  detail_html_gz BLOB,
+  detail_text    TEXT,
+  detail_fields  TEXT,
+  detail_images  TEXT,
+  captured_at    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS listing_changes (
+  id          TEXT PRIMARY KEY,
+  listing_id  TEXT REFERENCES listings(id) ON DELETE CASCADE,
+  field       TEXT NOT NULL,
+  old_value   TEXT,
+  new_value   TEXT,
+  changed_at  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sellers (
+  id              TEXT PRIMARY KEY,
+  profile_url     TEXT UNIQUE NOT NULL,
+  name            TEXT,
+  seller_type     TEXT NOT NULL DEFAULT 'private',
+  profile_markers TEXT NOT NULL DEFAULT '[]',
+  first_seen      TEXT NOT NULL,
+  last_seen       TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_listings_is_active      ON listings(is_active);
+CREATE INDEX IF NOT EXISTS idx_listings_make           ON listings(make);
+CREATE INDEX IF NOT EXISTS idx_listings_created_at     ON listings(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_listings_category       ON listings(category);
+CREATE INDEX IF NOT EXISTS idx_listings_seller_url     ON listings(seller_url);
+CREATE INDEX IF NOT EXISTS idx_price_history_listing   ON price_history(listing_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_scrape_runs_started     ON scrape_runs(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_snapshots_external      ON listing_snapshots(external_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_listing_changes         ON listing_changes(listing_id, changed_at DESC);
@@ -0,0 +1,7 @@
+-- SQLite version of migrations/0002_deal_scores.sql.
+
+ALTER TABLE listings ADD COLUMN expected_price REAL;
+ALTER TABLE listings ADD COLUMN deal_score     REAL;
+ALTER TABLE listings ADD COLUMN deal_label     TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_listings_deal_score ON listings(deal_score DESC);
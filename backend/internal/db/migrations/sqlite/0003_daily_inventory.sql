@@ -0,0 +1,14 @@
+-- SQLite version of migrations/0003_daily_inventory.sql. day is YYYY-MM-DD.
+
+CREATE TABLE IF NOT EXISTS daily_inventory (
+  day        TEXT NOT NULL,
+  listing_id TEXT NOT NULL REFERENCES listings(id) ON DELETE CASCADE,
+  category   TEXT NOT NULL,
+  make       TEXT,
+  body_type  TEXT,
+  price      REAL,
+  PRIMARY KEY (day, listing_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_listings_first_seen  ON listings(first_seen);
+CREATE INDEX IF NOT EXISTS idx_listings_delisted_at ON listings(delisted_at);
@@ -0,0 +1,3 @@
+-- SQLite version of migrations/0004_card_title.sql.
+
+ALTER TABLE listings ADD COLUMN card_title TEXT;
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// GetPriceHistory returns the recorded asking prices of one listing, oldest
+// first. id may be the listing's UUID or its external_id; an unknown listing
+// yields ErrListingNotFound.
+func GetPriceHistory(ctx context.Context, pool *pgxpool.Pool, id string) ([]models.PricePoint, error) {
+	listingID, err := resolveListingID(ctx, pool, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT price::float8, recorded_at
+		FROM price_history
+		WHERE listing_id = $1
+		ORDER BY recorded_at, id
+	`, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("query price history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.PricePoint
+	for rows.Next() {
+		var p models.PricePoint
+		if err := rows.Scan(&p.Price, &p.RecordedAt); err != nil {
+			return nil, fmt.Errorf("scan price history row: %w", err)
+		}
+		history = append(history, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return history, nil
+}
+
+// initialPriceSQL selects the original asking price of every listing that has
+// no initial price_history row yet (one recorded at or before first_seen).
+// With no history at all, the current price is the original. With history,
+// the original is the old value of the earliest price change in
+// listing_changes, trusted only when every history row has a matching change;
+// otherwise it is unrecoverable and NULL.
+const initialPriceSQL = `
+	SELECT l.id, l.first_seen,
+		CASE
+			WHEN h.n = 0 THEN l.price
+			WHEN h.n = c.n THEN c.first_old
+		END AS price
+	FROM listings l
+	CROSS JOIN LATERAL (
+		SELECT COUNT(*) AS n FROM price_history ph WHERE ph.listing_id = l.id
+	) h
+	CROSS JOIN LATERAL (
+		SELECT COUNT(*) AS n,
+			(ARRAY_AGG(lc.old_value ORDER BY lc.changed_at)
+				FILTER (WHERE lc.old_value IS NOT NULL))[1]::numeric AS first_old
+		FROM listing_changes lc
+		WHERE lc.listing_id = l.id AND lc.field = 'price'
+	) c
+	WHERE l.price > 0
+	  AND l.first_seen IS NOT NULL
+	  AND NOT EXISTS (
+		SELECT 1 FROM price_history ph
+		WHERE ph.listing_id = l.id AND ph.recorded_at <= l.first_seen
+	  )`
+
+// BackfillInitialPrices inserts the missing initial price_history row of every
+// listing, stamped with its first_seen, so price history starts at the
+// original asking price. It returns how many rows were (or, with dryRun,
+// would be) inserted and how many listings' original price could not be
+// recovered. It is idempotent.
+func BackfillInitialPrices(ctx context.Context, pool *pgxpool.Pool, dryRun bool) (inserted, unrecoverable int64, err error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin backfill: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM (`+initialPriceSQL+`) p WHERE p.price IS NULL`,
+	).Scan(&unrecoverable)
+	if err != nil {
+		return 0, 0, fmt.Errorf("count unrecoverable initial prices: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO price_history (listing_id, price, recorded_at)
+		SELECT p.id, p.price, p.first_seen
+		FROM (`+initialPriceSQL+`) p
+		WHERE p.price IS NOT NULL`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("backfill initial prices: %w", err)
+	}
+	inserted = tag.RowsAffected()
+
+	if dryRun {
+		return inserted, unrecoverable, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("commit backfill: %w", err)
+	}
+	return inserted, unrecoverable, nil
+}
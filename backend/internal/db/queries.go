@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -12,160 +13,270 @@ import (
 	"ecaycar/backend/models"
 )
 
-// UpsertResult describes what happened when a listing was upserted.
-type UpsertResult struct {
-	Inserted     bool
-	PriceChanged bool
+// ErrListingNotFound is returned when a listing looked up by ID does not exist.
+var ErrListingNotFound = errors.New("listing not found")
+
+// DeactivateUnseen marks every active listing in category whose external_id
+// is not in seenIDs as inactive and stamps delisted_at. It must only be called
+// with the IDs from a complete crawl of that category; a partial crawl would
+// delist live listings. Returns the number of listings deactivated.
+func DeactivateUnseen(ctx context.Context, pool *pgxpool.Pool, category string, seenIDs []string) (int64, error) {
+	tag, err := pool.Exec(ctx, `
+		UPDATE listings
+		SET is_active   = FALSE,
+			delisted_at = NOW(),
+			updated_at  = NOW()
+		WHERE is_active = TRUE
+		  AND category = $1
+		  AND NOT (external_id = ANY($2))`,
+		category, seenIDs,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deactivate unseen %s listings: %w", category, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetKnownListings returns the price and card title of every stored listing
+// keyed by external_id, for incremental scrapes to detect unchanged cards.
+// The card title is used because AI enrichment rewrites title; rows stored
+// before card_title existed fall back to title.
+func GetKnownListings(ctx context.Context, pool *pgxpool.Pool) (map[string]models.KnownListing, error) {
+	rows, err := pool.Query(ctx, `SELECT external_id, COALESCE(price, 0), COALESCE(card_title, title) FROM listings`)
+	if err != nil {
+		return nil, fmt.Errorf("query known listings: %w", err)
+	}
+	defer rows.Close()
+
+	known := make(map[string]models.KnownListing)
+	for rows.Next() {
+		var id string
+		var k models.KnownListing
+		if err := rows.Scan(&id, &k.Price, &k.Title); err != nil {
+			return nil, fmt.Errorf("scan known listing row: %w", err)
+		}
+		known[id] = k
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return known, nil
 }
 
-// UpsertListing inserts a new listing or updates the existing one matched on
-// external_id. If the price has changed, a price_history row is also inserted.
-func UpsertListing(ctx context.Context, pool *pgxpool.Pool, l models.Listing) (UpsertResult, error) {
-	var res UpsertResult
+// TouchListings marks listings that were seen unchanged as still live: it
+// bumps last_seen and reactivates them without rewriting any other column.
+func TouchListings(ctx context.Context, pool *pgxpool.Pool, externalIDs []string) (int64, error) {
+	tag, err := pool.Exec(ctx, `
+		UPDATE listings
+		SET last_seen   = NOW(),
+			is_active   = TRUE,
+			delisted_at = NULL
+		WHERE external_id = ANY($1)`,
+		externalIDs,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("touch listings: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
 
-	// ── 1. Check whether the listing already exists and what its current price is ──
-	var existingID string
-	var existingPrice float64
+// CountActiveListings returns the number of listings in category currently
+// marked active.
+func CountActiveListings(ctx context.Context, pool *pgxpool.Pool, category string) (int, error) {
+	var n int
 	err := pool.QueryRow(ctx,
-		`SELECT id, price FROM listings WHERE external_id = $1`,
+		`SELECT COUNT(*)::int FROM listings WHERE is_active = TRUE AND category = $1`,
+		category,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count active %s listings: %w", category, err)
+	}
+	return n, nil
+}
+
+// listingColumns is the column list scanned by scanListing, in order. It must
+// be selected FROM listings (unaliased) for the seller_type lookup.
+const listingColumns = `
+	id, external_id, category, url, title,
+	make, model, year, mileage,
+	price, currency, condition, transmission,
+	fuel_type, color, body_type, drive,
+	cylinders, steering, interior_color, doors, on_island,
+	attributes, description, images,
+	location, seller_name, seller_url,
+	(SELECT s.seller_type FROM sellers s WHERE s.profile_url = listings.seller_url),
+	posted_at, ad_updated_at, is_active,
+	first_seen, last_seen, delisted_at, created_at, updated_at,
+	expected_price, deal_score, deal_label`
+
+// GetListings returns one page of the active listings matching f, in f's
+// order, together with the total number of matches across all pages.
+// Listings whose seller is unknown never match a SellerType filter.
+func GetListings(ctx context.Context, pool *pgxpool.Pool, f ListingFilter) ([]models.Listing, int, error) {
+	where, args := f.where("$%d")
+
+	var total int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*)::int FROM listings `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count listings: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT `+listingColumns+`
+		FROM listings
+		`+where+`
+		`+f.orderBy()+`
+		`+f.page("ALL"), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query listings: %w", err)
+	}
+	listings, err := collectListings(rows)
+	return listings, total, err
+}
+
+// GetListing returns one listing, active or not, identified by its UUID or its
+// external_id. An unknown listing yields ErrListingNotFound.
+func GetListing(ctx context.Context, pool *pgxpool.Pool, id string) (models.Listing, error) {
+	row := pool.QueryRow(ctx, `
+		SELECT `+listingColumns+`
+		FROM listings
+		WHERE id::text = $1 OR external_id = $1
+		LIMIT 1
+	`, id)
+	l, err := scanListing(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return l, ErrListingNotFound
+	}
+	return l, err
+}
+
+// GetListingsByExternalID returns the listings (active or not) with the given
+// external IDs, keyed by external_id. Unknown IDs are simply absent.
+func GetListingsByExternalID(ctx context.Context, pool *pgxpool.Pool, externalIDs []string) (map[string]models.Listing, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT `+listingColumns+`
+		FROM listings
+		WHERE external_id = ANY($1)
+	`, externalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query listings by external_id: %w", err)
+	}
+	listings, err := collectListings(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]models.Listing, len(listings))
+	for _, l := range listings {
+		byID[l.ExternalID] = l
+	}
+	return byID, nil
+}
+
+// UpdateParsedFields overwrites the parser-derived columns of an existing
+// listing, matched on external_id. Unlike UpsertListing it leaves is_active
+// and last_seen alone: it corrects how a listing was read, not what was
+// observed on the site. A corrected price therefore adds no price_history or
+// listing_changes row; instead the newest price_history row, which recorded
+// the misread price, is corrected in place so history keeps agreeing with
+// listings.price without a fake price change.
+func UpdateParsedFields(ctx context.Context, pool *pgxpool.Pool, l models.Listing) error {
+	attrs, err := attributesJSON(l)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin update parsed fields %s: %w", l.ExternalID, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id string
+	var oldPrice float64
+	err = tx.QueryRow(ctx, `
+		SELECT id, price FROM listings WHERE external_id = $1 FOR UPDATE`,
 		l.ExternalID,
-	).Scan(&existingID, &existingPrice)
-
-	switch {
-	case errors.Is(err, pgx.ErrNoRows):
-		res.Inserted = true
-	case err != nil:
-		return res, fmt.Errorf("check existing listing %s: %w", l.ExternalID, err)
-	}
-
-	// ── 2. Upsert ──
-	var returnedID string
-	err = pool.QueryRow(ctx, `
-		INSERT INTO listings
-			(external_id, url, title, make, model, year, mileage, price, currency,
-			 images, location, condition, transmission, fuel_type, color,
-			 body_type, drive, cylinders, steering, interior_color, doors, on_island,
-			 is_active, last_seen)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,TRUE,NOW())
-		ON CONFLICT (external_id) DO UPDATE SET
-			url            = EXCLUDED.url,
-			title          = EXCLUDED.title,
-			make           = EXCLUDED.make,
-			model          = EXCLUDED.model,
-			year           = EXCLUDED.year,
-			mileage        = EXCLUDED.mileage,
-			price          = EXCLUDED.price,
-			currency       = EXCLUDED.currency,
-			images         = EXCLUDED.images,
-			location       = EXCLUDED.location,
-			condition      = EXCLUDED.condition,
-			transmission   = EXCLUDED.transmission,
-			fuel_type      = EXCLUDED.fuel_type,
-			color          = EXCLUDED.color,
-			body_type      = EXCLUDED.body_type,
-			drive          = EXCLUDED.drive,
-			cylinders      = EXCLUDED.cylinders,
-			steering       = EXCLUDED.steering,
-			interior_color = EXCLUDED.interior_color,
-			doors          = EXCLUDED.doors,
-			on_island      = EXCLUDED.on_island,
-			is_active      = TRUE,
-			last_seen      = NOW(),
+	).Scan(&id, &oldPrice)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load listing %s: %w", l.ExternalID, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE listings SET
+			title          = $2,
+			make           = $3,
+			model          = $4,
+			year           = $5,
+			mileage        = $6,
+			price          = $7,
+			currency       = $8,
+			images         = $9,
+			location       = $10,
+			condition      = $11,
+			transmission   = $12,
+			fuel_type      = $13,
+			color          = $14,
+			body_type      = $15,
+			drive          = $16,
+			cylinders      = $17,
+			steering       = $18,
+			interior_color = $19,
+			doors          = $20,
+			on_island      = $21,
+			attributes     = $22::jsonb,
+			description    = NULLIF($23, ''),
+			seller_name    = NULLIF($24, ''),
+			seller_url     = NULLIF($25, ''),
+			posted_at      = $26,
+			ad_updated_at  = $27,
+			card_title     = COALESCE(NULLIF($28, ''), card_title),
 			updated_at     = NOW()
-		RETURNING id`,
-		l.ExternalID, l.URL, l.Title, l.Make, l.Model,
+		WHERE id = $1`,
+		id, l.Title, l.Make, l.Model,
 		l.Year, l.Mileage, l.Price, l.Currency,
 		l.Images, l.Location, l.Condition, l.Transmission, l.FuelType, l.Color,
 		l.BodyType, l.Drive, l.Cylinders, l.Steering, l.InteriorColor, l.Doors, l.OnIsland,
-	).Scan(&returnedID)
+		attrs, l.Description, l.SellerName, l.SellerURL, l.PostedAt, l.AdUpdatedAt,
+		l.CardTitle,
+	)
 	if err != nil {
-		return res, fmt.Errorf("upsert listing %s: %w", l.ExternalID, err)
+		return fmt.Errorf("update parsed fields %s: %w", l.ExternalID, err)
 	}
 
-	// ── 3. Record price history when the price has actually changed ──
-	if !res.Inserted && existingPrice != l.Price && l.Price > 0 {
-		res.PriceChanged = true
-		_, err = pool.Exec(ctx,
-			`INSERT INTO price_history (listing_id, price) VALUES ($1, $2)`,
-			returnedID, l.Price,
-		)
-		if err != nil {
-			return res, fmt.Errorf("insert price_history for %s: %w", l.ExternalID, err)
+	if oldPrice != l.Price && l.Price > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE price_history SET price = $2
+			WHERE id = (
+				SELECT id FROM price_history
+				WHERE listing_id = $1
+				ORDER BY recorded_at DESC
+				LIMIT 1
+			)`,
+			id, l.Price,
+		); err != nil {
+			return fmt.Errorf("correct price history for %s: %w", l.ExternalID, err)
 		}
 	}
 
-	return res, nil
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit update parsed fields %s: %w", l.ExternalID, err)
+	}
+	return nil
 }
 
-// GetListings returns all active listings ordered newest-first.
-func GetListings(ctx context.Context, pool *pgxpool.Pool) ([]models.Listing, error) {
-	rows, err := pool.Query(ctx, `
-		SELECT
-			id, external_id, url, title,
-			make, model, year, mileage,
-			price, currency, condition, transmission,
-			fuel_type, color, body_type, drive,
-			cylinders, steering, interior_color, doors, on_island,
-			description, images,
-			location, seller_name, is_active,
-			first_seen, last_seen, created_at, updated_at
-		FROM listings
-		WHERE is_active = TRUE
-		ORDER BY created_at DESC
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("query listings: %w", err)
-	}
+// collectListings scans every row selected with listingColumns and closes rows.
+func collectListings(rows pgx.Rows) ([]models.Listing, error) {
 	defer rows.Close()
 
 	var listings []models.Listing
 	for rows.Next() {
-		var (
-			l models.Listing
-			// Nullable text columns.
-			make_, model_, condition_, transmission_ *string
-			fuelType_, color_, bodyType_, drive_     *string
-			cylinders_, steering_, interiorColor_    *string
-			doors_, description_, location_          *string
-			sellerName_                              *string
-			// Nullable timestamptz columns.
-			firstSeen_, lastSeen_, createdAt_, updatedAt_ *time.Time
-		)
-
-		err := rows.Scan(
-			&l.ID, &l.ExternalID, &l.URL, &l.Title,
-			&make_, &model_, &l.Year, &l.Mileage,
-			&l.Price, &l.Currency, &condition_, &transmission_,
-			&fuelType_, &color_, &bodyType_, &drive_,
-			&cylinders_, &steering_, &interiorColor_, &doors_, &l.OnIsland,
-			&description_, &l.Images,
-			&location_, &sellerName_, &l.IsActive,
-			&firstSeen_, &lastSeen_, &createdAt_, &updatedAt_,
-		)
+		l, err := scanListing(rows)
 		if err != nil {
-			return nil, fmt.Errorf("scan listing row: %w", err)
+			return nil, err
 		}
-
-		l.Make = strVal(make_)
-		l.Model = strVal(model_)
-		l.Condition = strVal(condition_)
-		l.Transmission = strVal(transmission_)
-		l.FuelType = strVal(fuelType_)
-		l.Color = strVal(color_)
-		l.BodyType = strVal(bodyType_)
-		l.Drive = strVal(drive_)
-		l.Cylinders = strVal(cylinders_)
-		l.Steering = strVal(steering_)
-		l.InteriorColor = strVal(interiorColor_)
-		l.Doors = strVal(doors_)
-		l.Description = strVal(description_)
-		l.Location = strVal(location_)
-		l.SellerName = strVal(sellerName_)
-		l.FirstSeen = firstSeen_
-		l.LastSeen = lastSeen_
-		l.CreatedAt = createdAt_
-		l.UpdatedAt = updatedAt_
-
 		listings = append(listings, l)
 	}
 
@@ -175,10 +286,74 @@ func GetListings(ctx context.Context, pool *pgxpool.Pool) ([]models.Listing, err
 	return listings, nil
 }
 
+// scanListing scans a single row selected with listingColumns.
+func scanListing(row pgx.Row) (models.Listing, error) {
+	var (
+		l models.Listing
+		// Nullable text columns.
+		make_, model_, condition_, transmission_ *string
+		fuelType_, color_, bodyType_, drive_     *string
+		cylinders_, steering_, interiorColor_    *string
+		doors_, description_, location_          *string
+		sellerName_, sellerURL_, sellerType_     *string
+		dealLabel_                               *string
+		attributesJSON_                          []byte
+		// Nullable timestamptz columns.
+		firstSeen_, lastSeen_, delistedAt_, createdAt_, updatedAt_ *time.Time
+	)
+
+	err := row.Scan(
+		&l.ID, &l.ExternalID, &l.Category, &l.URL, &l.Title,
+		&make_, &model_, &l.Year, &l.Mileage,
+		&l.Price, &l.Currency, &condition_, &transmission_,
+		&fuelType_, &color_, &bodyType_, &drive_,
+		&cylinders_, &steering_, &interiorColor_, &doors_, &l.OnIsland,
+		&attributesJSON_, &description_, &l.Images,
+		&location_, &sellerName_, &sellerURL_, &sellerType_, &l.PostedAt, &l.AdUpdatedAt, &l.IsActive,
+		&firstSeen_, &lastSeen_, &delistedAt_, &createdAt_, &updatedAt_,
+		&l.ExpectedPrice, &l.DealScore, &dealLabel_,
+	)
+	if err != nil {
+		return l, fmt.Errorf("scan listing row: %w", err)
+	}
+	if len(attributesJSON_) > 0 {
+		if err := json.Unmarshal(attributesJSON_, &l.Attributes); err != nil {
+			return l, fmt.Errorf("unmarshal attributes %s: %w", l.ExternalID, err)
+		}
+	}
+
+	l.Make = strVal(make_)
+	l.Model = strVal(model_)
+	l.Condition = strVal(condition_)
+	l.Transmission = strVal(transmission_)
+	l.FuelType = strVal(fuelType_)
+	l.Color = strVal(color_)
+	l.BodyType = strVal(bodyType_)
+	l.Drive = strVal(drive_)
+	l.Cylinders = strVal(cylinders_)
+	l.Steering = strVal(steering_)
+	l.InteriorColor = strVal(interiorColor_)
+	l.Doors = strVal(doors_)
+	l.Description = strVal(description_)
+	l.Location = strVal(location_)
+	l.SellerName = strVal(sellerName_)
+	l.SellerURL = strVal(sellerURL_)
+	l.SellerType = strVal(sellerType_)
+	l.DealLabel = strVal(dealLabel_)
+	l.FirstSeen = firstSeen_
+	l.LastSeen = lastSeen_
+	l.DelistedAt = delistedAt_
+	l.CreatedAt = createdAt_
+	l.UpdatedAt = updatedAt_
+
+	return l, nil
+}
+
 // GetStats returns pre-computed dashboard statistics: total listing count, average
 // price, median price, new-this-week count, average mileage, top 8 makes, body
-// type distribution, and year distribution.
-func GetStats(ctx context.Context, pool *pgxpool.Pool) (models.Stats, error) {
+// type distribution, and year distribution. A non-empty category limits every
+// figure to that category.
+func GetStats(ctx context.Context, pool *pgxpool.Pool, category string) (models.Stats, error) {
 	var stats models.Stats
 
 	// Single-row aggregates.
@@ -190,8 +365,8 @@ func GetStats(ctx context.Context, pool *pgxpool.Pool) (models.Stats, error) {
 			COUNT(*) FILTER (WHERE first_seen >= NOW() - INTERVAL '7 days')::int,
 			COALESCE(AVG(mileage) FILTER (WHERE mileage IS NOT NULL), 0)
 		FROM listings
-		WHERE is_active = TRUE
-	`).Scan(&stats.TotalListings, &stats.AvgPrice, &stats.MedianPrice, &stats.NewThisWeek, &stats.AvgMileage)
+		WHERE is_active = TRUE AND ($1 = '' OR category = $1)
+	`, category).Scan(&stats.TotalListings, &stats.AvgPrice, &stats.MedianPrice, &stats.NewThisWeek, &stats.AvgMileage)
 	if err != nil {
 		return stats, fmt.Errorf("get stats aggregate: %w", err)
 	}
@@ -200,11 +375,11 @@ func GetStats(ctx context.Context, pool *pgxpool.Pool) (models.Stats, error) {
 	brandRows, err := pool.Query(ctx, `
 		SELECT make, COUNT(*)::int, COALESCE(AVG(price), 0)
 		FROM listings
-		WHERE is_active = TRUE AND make IS NOT NULL AND make != ''
+		WHERE is_active = TRUE AND ($1 = '' OR category = $1) AND make IS NOT NULL AND make != ''
 		GROUP BY make
 		ORDER BY COUNT(*) DESC
 		LIMIT 8
-	`)
+	`, category)
 	if err != nil {
 		return stats, fmt.Errorf("get top brands: %w", err)
 	}
@@ -231,10 +406,10 @@ func GetStats(ctx context.Context, pool *pgxpool.Pool) (models.Stats, error) {
 			COUNT(*)::int,
 			COALESCE(AVG(price), 0)
 		FROM listings
-		WHERE is_active = TRUE
+		WHERE is_active = TRUE AND ($1 = '' OR category = $1)
 		GROUP BY bt
 		ORDER BY COUNT(*) DESC
-	`)
+	`, category)
 	if err != nil {
 		return stats, fmt.Errorf("get body types: %w", err)
 	}
@@ -258,10 +433,10 @@ func GetStats(ctx context.Context, pool *pgxpool.Pool) (models.Stats, error) {
 	yrRows, err := pool.Query(ctx, `
 		SELECT year::int, COUNT(*)::int
 		FROM listings
-		WHERE is_active = TRUE AND year IS NOT NULL
+		WHERE is_active = TRUE AND ($1 = '' OR category = $1) AND year IS NOT NULL
 		GROUP BY year
 		ORDER BY year ASC
-	`)
+	`, category)
 	if err != nil {
 		return stats, fmt.Errorf("get year distribution: %w", err)
 	}
@@ -284,6 +459,29 @@ func GetStats(ctx context.Context, pool *pgxpool.Pool) (models.Stats, error) {
 	return stats, nil
 }
 
+// attributesJSON encodes l.Attributes for a jsonb parameter, or nil (SQL NULL)
+// when the listing has none.
+func attributesJSON(l models.Listing) (*string, error) {
+	if len(l.Attributes) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(l.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("marshal attributes %s: %w", l.ExternalID, err)
+	}
+	s := string(b)
+	return &s, nil
+}
+
+// categoryOrDefault returns category, or "autos" for listings parsed before
+// categories existed.
+func categoryOrDefault(category string) string {
+	if category == "" {
+		return "autos"
+	}
+	return category
+}
+
 // strVal dereferences a *string, returning "" for nil pointers.
 func strVal(s *string) string {
 	if s == nil {
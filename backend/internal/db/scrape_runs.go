@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// StartScrapeRun inserts a scrape_runs row in the "running" state and returns
+// its ID. The row is completed later by FinishScrapeRun.
+func StartScrapeRun(ctx context.Context, pool *pgxpool.Pool, run models.ScrapeRun) (string, error) {
+	var id string
+	err := pool.QueryRow(ctx,
+		`INSERT INTO scrape_runs (started_at, status) VALUES ($1, $2) RETURNING id`,
+		run.StartedAt, run.Status,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("insert scrape run: %w", err)
+	}
+	return id, nil
+}
+
+// FinishScrapeRun writes the final counters and status for the run with the given ID.
+func FinishScrapeRun(ctx context.Context, pool *pgxpool.Pool, id string, run models.ScrapeRun) error {
+	_, err := pool.Exec(ctx, `
+		UPDATE scrape_runs SET
+			finished_at              = $2,
+			status                   = $3,
+			complete                 = $4,
+			pages_visited            = $5,
+			raw_cards                = $6,
+			accepted                 = $7,
+			unchanged                = $8,
+			skipped_empty            = $9,
+			skipped_no_year          = $10,
+			skipped_low_price        = $11,
+			skipped_price_on_request = $12,
+			detail_failures          = $13,
+			blocked_pages            = $14,
+			enrich_calls             = $15,
+			enrich_failures          = $16,
+			inserted                 = $17,
+			updated                  = $18,
+			price_changed            = $19,
+			upsert_errors            = $20,
+			delisted                 = $21,
+			error                    = NULLIF($22, '')
+		WHERE id = $1`,
+		id, run.FinishedAt, run.Status, run.Complete,
+		run.PagesVisited, run.RawCards, run.Accepted, run.Unchanged,
+		run.SkippedEmpty, run.SkippedNoYear, run.SkippedLowPrice, run.SkippedPriceOnRequest,
+		run.DetailFailures, run.BlockedPages, run.EnrichCalls, run.EnrichFailures,
+		run.Inserted, run.Updated, run.PriceChanged, run.UpsertErrors,
+		run.Delisted, run.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("finish scrape run %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetScrapeRuns returns the most recent scrape runs, newest first.
+func GetScrapeRuns(ctx context.Context, pool *pgxpool.Pool, limit int) ([]models.ScrapeRun, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT
+			id, started_at, finished_at, status, complete,
+			pages_visited, raw_cards, accepted, unchanged,
+			skipped_empty, skipped_no_year, skipped_low_price, skipped_price_on_request,
+			detail_failures, blocked_pages, enrich_calls, enrich_failures,
+			inserted, updated, price_changed, upsert_errors,
+			delisted, COALESCE(error, '')
+		FROM scrape_runs
+		ORDER BY started_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query scrape runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.ScrapeRun
+	for rows.Next() {
+		var r models.ScrapeRun
+		err := rows.Scan(
+			&r.ID, &r.StartedAt, &r.FinishedAt, &r.Status, &r.Complete,
+			&r.PagesVisited, &r.RawCards, &r.Accepted, &r.Unchanged,
+			&r.SkippedEmpty, &r.SkippedNoYear, &r.SkippedLowPrice, &r.SkippedPriceOnRequest,
+			&r.DetailFailures, &r.BlockedPages, &r.EnrichCalls, &r.EnrichFailures,
+			&r.Inserted, &r.Updated, &r.PriceChanged, &r.UpsertErrors,
+			&r.Delisted, &r.Error,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan scrape run row: %w", err)
+		}
+		runs = append(runs, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return runs, nil
+}
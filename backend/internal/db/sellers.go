@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// UpsertSellers records the sellers seen in a scrape run, keyed by profile
+// URL. A blank name never replaces a stored one, and profile markers are
+// merged with those already stored. New sellers start as private until
+// classified.
+func UpsertSellers(ctx context.Context, pool *pgxpool.Pool, sellers []models.Seller) error {
+	for _, s := range sellers {
+		markers := s.ProfileMarkers
+		if markers == nil {
+			markers = []string{}
+		}
+		_, err := pool.Exec(ctx, `
+			INSERT INTO sellers (profile_url, name, profile_markers)
+			VALUES ($1, NULLIF($2, ''), $3)
+			ON CONFLICT (profile_url) DO UPDATE SET
+				name            = COALESCE(EXCLUDED.name, sellers.name),
+				profile_markers = ARRAY(
+					SELECT DISTINCT m FROM unnest(sellers.profile_markers || EXCLUDED.profile_markers) AS m
+				),
+				last_seen       = NOW()`,
+			s.ProfileURL, s.Name, markers,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert seller %s: %w", s.ProfileURL, err)
+		}
+	}
+	return nil
+}
+
+// GetSellers returns every seller, or only those of sellerType when it is
+// non-empty, with figures computed from their listings: total and active
+// listing counts, average active price, and average days on market measured
+// from the ad's posted date (or first_seen) to delisting (or now). Sellers
+// with the largest active inventory come first.
+func GetSellers(ctx context.Context, pool *pgxpool.Pool, sellerType string) ([]models.Seller, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT
+			s.id, s.profile_url, COALESCE(s.name, ''), s.seller_type, s.profile_markers,
+			COUNT(l.id)::int,
+			COUNT(l.id) FILTER (WHERE l.is_active)::int,
+			COALESCE(AVG(l.price) FILTER (WHERE l.is_active), 0)::float8,
+			COALESCE(AVG(EXTRACT(EPOCH FROM
+				COALESCE(l.delisted_at, CASE WHEN l.is_active THEN NOW() ELSE l.last_seen END)
+				- COALESCE(l.posted_at, l.first_seen)
+			) / 86400), 0)::float8,
+			s.first_seen, s.last_seen
+		FROM sellers s
+		LEFT JOIN listings l ON l.seller_url = s.profile_url
+		WHERE $1 = '' OR s.seller_type = $1
+		GROUP BY s.id
+		ORDER BY COUNT(l.id) FILTER (WHERE l.is_active) DESC, s.name
+	`, sellerType)
+	if err != nil {
+		return nil, fmt.Errorf("query sellers: %w", err)
+	}
+	defer rows.Close()
+
+	var sellers []models.Seller
+	for rows.Next() {
+		var s models.Seller
+		err := rows.Scan(
+			&s.ID, &s.ProfileURL, &s.Name, &s.SellerType, &s.ProfileMarkers,
+			&s.ListingCount, &s.InventoryCount, &s.AvgPrice, &s.AvgDaysOnMarket,
+			&s.FirstSeen, &s.LastSeen,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan seller row: %w", err)
+		}
+		sellers = append(sellers, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return sellers, nil
+}
+
+// SetSellerType stores the classifier's verdict for one seller.
+func SetSellerType(ctx context.Context, pool *pgxpool.Pool, profileURL, sellerType string) error {
+	_, err := pool.Exec(ctx,
+		`UPDATE sellers SET seller_type = $2 WHERE profile_url = $1`,
+		profileURL, sellerType,
+	)
+	if err != nil {
+		return fmt.Errorf("set seller type %s: %w", profileURL, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// InsertListingSnapshot archives the raw card and detail page captured for one
+// listing in one scrape run. Detail HTML is gzip-compressed before storage.
+// runID may be empty when the run itself could not be recorded.
+func InsertListingSnapshot(ctx context.Context, pool *pgxpool.Pool, runID string, s models.ListingSnapshot) error {
+	html, err := gzipString(s.DetailHTML)
+	if err != nil {
+		return fmt.Errorf("compress snapshot %s: %w", s.ExternalID, err)
+	}
+	fields, err := json.Marshal(s.DetailFields)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot fields %s: %w", s.ExternalID, err)
+	}
+
+	_, err = pool.Exec(ctx, `
+		INSERT INTO listing_snapshots
+			(run_id, external_id, category, url, card_text, img_src,
+			 detail_html_gz, detail_text, detail_fields, detail_images)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9::jsonb, $10)`,
+		runID, s.ExternalID, categoryOrDefault(s.Category), s.URL, s.CardText, s.ImgSrc,
+		html, s.DetailText, string(fields), s.DetailImages,
+	)
+	if err != nil {
+		return fmt.Errorf("insert snapshot %s: %w", s.ExternalID, err)
+	}
+	return nil
+}
+
+// PruneListingSnapshots deletes snapshots captured before the retention
+// window, always keeping the newest snapshot of each listing so every listing
+// can still be re-parsed. Returns the number of snapshots deleted.
+func PruneListingSnapshots(ctx context.Context, pool *pgxpool.Pool, retention time.Duration) (int64, error) {
+	tag, err := pool.Exec(ctx, `
+		DELETE FROM listing_snapshots s
+		WHERE s.captured_at < $1
+		  AND EXISTS (
+			SELECT 1 FROM listing_snapshots n
+			WHERE n.external_id = s.external_id
+			  AND n.captured_at > s.captured_at
+		  )`,
+		time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("prune listing snapshots: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetLatestSnapshots returns the newest archived snapshot of every listing,
+// or only of externalID when it is non-empty. Detail HTML is not loaded: the
+// parser works from the card text, detail text, detail field map and gallery.
+func GetLatestSnapshots(ctx context.Context, pool *pgxpool.Pool, externalID string) ([]models.ListingSnapshot, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT DISTINCT ON (external_id)
+			id, COALESCE(run_id::text, ''), external_id, category, url, card_text,
+			COALESCE(img_src, ''), COALESCE(detail_text, ''), detail_fields, detail_images, captured_at
+		FROM listing_snapshots
+		WHERE $1 = '' OR external_id = $1
+		ORDER BY external_id, captured_at DESC
+	`, externalID)
+	if err != nil {
+		return nil, fmt.Errorf("query latest snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snaps []models.ListingSnapshot
+	for rows.Next() {
+		var (
+			s          models.ListingSnapshot
+			fieldsJSON []byte
+			capturedAt time.Time
+		)
+		err := rows.Scan(
+			&s.ID, &s.RunID, &s.ExternalID, &s.Category, &s.URL, &s.CardText,
+			&s.ImgSrc, &s.DetailText, &fieldsJSON, &s.DetailImages, &capturedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan snapshot row: %w", err)
+		}
+		if len(fieldsJSON) > 0 {
+			if err := json.Unmarshal(fieldsJSON, &s.DetailFields); err != nil {
+				return nil, fmt.Errorf("unmarshal snapshot fields %s: %w", s.ExternalID, err)
+			}
+		}
+		s.CapturedAt = &capturedAt
+		snaps = append(snaps, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return snaps, nil
+}
+
+// gzipString compresses s, returning nil for an empty string.
+func gzipString(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,1045 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+
+	"ecaycar/backend/models"
+)
+
+// sqliteTimeLayout is how SQLiteStore stores timestamps: UTC with fixed-width
+// fractional seconds, so text order is time order and julianday() can read it.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// SQLiteStore is a ScrapeStore backed by a local SQLite file, so the API and
+// a replay-mode scraper can run with no database server. It uses the SQLite
+// migrations in migrations/sqlite, applied when the store is opened.
+type SQLiteStore struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+var _ ScrapeStore = (*SQLiteStore)(nil)
+
+// sqlQueryer is the part of *sql.DB and *sql.Tx the store's helpers use.
+type sqlQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// OpenSQLite opens (creating if needed) the SQLite database at path and
+// applies any pending SQLite migrations.
+func OpenSQLite(ctx context.Context, path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+
+		"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("db: open sqlite %s: %w", path, err)
+	}
+	// One connection serialises writers, which SQLite requires anyway, and
+	// keeps an in-memory database from being one database per connection.
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db, now: time.Now}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies the pending migrations in migrations/sqlite, each in its
+// own transaction with its schema_migrations row.
+func (s *SQLiteStore) migrate(ctx context.Context) error {
+	migrations, err := readMigrations("migrations/sqlite")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			var done bool
+			err := tx.QueryRowContext(ctx,
+				`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, m.Version,
+			).Scan(&done)
+			if err != nil || done {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.Version, m.Name, sqlTime(s.now()),
+			)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("sqlite migration %d: %w", m.Version, err)
+		}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing when it returns nil.
+func (s *SQLiteStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStore) Close() {
+	_ = s.db.Close()
+}
+
+// ── Listings ──
+
+// UpsertListings has the semantics of the package-level UpsertListings: one
+// transaction, a seed price_history row for new listings, a row per price
+// change, listing_changes for every changed field, and detail-only fields
+// kept when a run didn't capture them.
+func (s *SQLiteStore) UpsertListings(ctx context.Context, listings []models.Listing) ([]UpsertResult, error) {
+	results := make([]UpsertResult, len(listings))
+	now := sqlTime(s.now())
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for i, l := range listings {
+			prev, err := s.getListing(ctx, tx, `external_id = ?`, l.ExternalID)
+			inserted := errors.Is(err, ErrListingNotFound)
+			if err != nil && !inserted {
+				return err
+			}
+
+			id := prev.ID
+			if inserted {
+				id = newUUID()
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO listings (id, external_id, url, title, first_seen, created_at)
+					VALUES (?, ?, '', '', ?, ?)`,
+					id, l.ExternalID, now, now,
+				)
+				if err != nil {
+					return fmt.Errorf("insert listing %s: %w", l.ExternalID, err)
+				}
+			} else {
+				l = keepStoredDetails(l, prev)
+			}
+
+			if err := sqliteWriteListing(ctx, tx, l, now); err != nil {
+				return err
+			}
+
+			res := &results[i]
+			if inserted {
+				res.Inserted = true
+			} else {
+				res.Changes = DiffListings(prev, l)
+				res.PriceChanged = prev.Price != l.Price && l.Price > 0
+			}
+			if (res.Inserted || res.PriceChanged) && l.Price > 0 {
+				err := execSQLite(ctx, tx, l.ExternalID,
+					`INSERT INTO price_history (id, listing_id, price, recorded_at) VALUES (?, ?, ?, ?)`,
+					newUUID(), id, l.Price, now)
+				if err != nil {
+					return err
+				}
+			}
+			for _, c := range res.Changes {
+				err := execSQLite(ctx, tx, l.ExternalID, `
+					INSERT INTO listing_changes (id, listing_id, field, old_value, new_value, changed_at)
+					VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)`,
+					newUUID(), id, c.Field, c.Old, c.New, now)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upsert listings: %w", err)
+	}
+	return results, nil
+}
+
+// sqliteWriteListing overwrites the scraped columns of the row with l's
+// external_id and marks it live.
+func sqliteWriteListing(ctx context.Context, tx *sql.Tx, l models.Listing, now string) error {
+	attrs, err := attributesJSON(l)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE listings SET
+			url = ?, title = ?, make = ?, model = ?, year = ?, mileage = ?,
+			price = ?, currency = ?, images = ?, location = ?, condition = ?,
+			transmission = ?, fuel_type = ?, color = ?, body_type = ?, drive = ?,
+			cylinders = ?, steering = ?, interior_color = ?, doors = ?, on_island = ?,
+			category = ?, attributes = ?, description = NULLIF(?, ''),
+			seller_name = NULLIF(?, ''), seller_url = NULLIF(?, ''),
+			posted_at = ?, ad_updated_at = ?, card_title = COALESCE(NULLIF(?, ''), card_title),
+			is_active = 1, last_seen = ?, delisted_at = NULL, updated_at = ?
+		WHERE external_id = ?`,
+		l.URL, l.Title, l.Make, l.Model, l.Year, l.Mileage,
+		l.Price, l.Currency, jsonText(l.Images), l.Location, l.Condition,
+		l.Transmission, l.FuelType, l.Color, l.BodyType, l.Drive,
+		l.Cylinders, l.Steering, l.InteriorColor, l.Doors, l.OnIsland,
+		categoryOrDefault(l.Category), attrs, l.Description,
+		l.SellerName, l.SellerURL,
+		sqlTimePtr(l.PostedAt), sqlTimePtr(l.AdUpdatedAt), l.CardTitle,
+		now, now, l.ExternalID,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert listing %s: %w", l.ExternalID, err)
+	}
+	return nil
+}
+
+// execSQLite runs one history statement, naming the listing in the error.
+func execSQLite(ctx context.Context, tx *sql.Tx, externalID, query string, args ...any) error {
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("record history for %s: %w", externalID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetListings(ctx context.Context, f ListingFilter) ([]models.Listing, int, error) {
+	where, args := f.where("?%d")
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM listings `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count listings: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+listingColumns+`
+		FROM listings
+		`+where+`
+		`+f.orderBy()+`
+		`+f.page("-1"), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query listings: %w", err)
+	}
+	listings, err := collectSQLiteListings(rows)
+	return listings, total, err
+}
+
+func (s *SQLiteStore) GetListing(ctx context.Context, id string) (models.Listing, error) {
+	return s.getListing(ctx, s.db, `id = ?1 OR external_id = ?1`, id)
+}
+
+// getListing returns the first listing matching where, or ErrListingNotFound.
+func (s *SQLiteStore) getListing(ctx context.Context, q sqlQueryer, where string, args ...any) (models.Listing, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+listingColumns+` FROM listings WHERE `+where+` LIMIT 1`, args...)
+	if err != nil {
+		return models.Listing{}, fmt.Errorf("query listing: %w", err)
+	}
+	listings, err := collectSQLiteListings(rows)
+	if err != nil {
+		return models.Listing{}, err
+	}
+	if len(listings) == 0 {
+		return models.Listing{}, ErrListingNotFound
+	}
+	return listings[0], nil
+}
+
+func (s *SQLiteStore) GetPriceHistory(ctx context.Context, id string) ([]models.PricePoint, error) {
+	l, err := s.GetListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT price, recorded_at
+		FROM price_history
+		WHERE listing_id = ?
+		ORDER BY recorded_at, rowid
+	`, l.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query price history: %w", err)
+	}
+	var history []models.PricePoint
+	err = collectRows(rows, func(r *sql.Rows) error {
+		var (
+			p          models.PricePoint
+			recordedAt string
+		)
+		if err := r.Scan(&p.Price, &recordedAt); err != nil {
+			return fmt.Errorf("scan price history row: %w", err)
+		}
+		recorded, err := parseSQLTime(recordedAt)
+		if err != nil {
+			return err
+		}
+		p.RecordedAt = recorded
+		history = append(history, p)
+		return nil
+	})
+	return history, err
+}
+
+func (s *SQLiteStore) GetListingChanges(ctx context.Context, id string) ([]models.ListingChange, error) {
+	l, err := s.GetListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT field, COALESCE(old_value, ''), COALESCE(new_value, ''), changed_at
+		FROM listing_changes
+		WHERE listing_id = ?
+		ORDER BY changed_at DESC, field
+	`, l.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query listing changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.ListingChange
+	for rows.Next() {
+		var (
+			c         models.ListingChange
+			changedAt string
+		)
+		if err := rows.Scan(&c.Field, &c.Old, &c.New, &changedAt); err != nil {
+			return nil, fmt.Errorf("scan listing change row: %w", err)
+		}
+		if c.ChangedAt, err = parseSQLTime(changedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return changes, nil
+}
+
+func (s *SQLiteStore) GetKnownListings(ctx context.Context) (map[string]models.KnownListing, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT external_id, COALESCE(price, 0), COALESCE(card_title, title) FROM listings`)
+	if err != nil {
+		return nil, fmt.Errorf("query known listings: %w", err)
+	}
+	defer rows.Close()
+
+	known := make(map[string]models.KnownListing)
+	for rows.Next() {
+		var id string
+		var k models.KnownListing
+		if err := rows.Scan(&id, &k.Price, &k.Title); err != nil {
+			return nil, fmt.Errorf("scan known listing row: %w", err)
+		}
+		known[id] = k
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return known, nil
+}
+
+func (s *SQLiteStore) TouchListings(ctx context.Context, externalIDs []string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE listings
+		SET last_seen = ?, is_active = 1, delisted_at = NULL
+		WHERE external_id IN (SELECT value FROM json_each(?))`,
+		sqlTime(s.now()), jsonText(externalIDs),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("touch listings: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *SQLiteStore) CountActiveListings(ctx context.Context, category string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM listings WHERE is_active = 1 AND category = ?`, category,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count active %s listings: %w", category, err)
+	}
+	return n, nil
+}
+
+func (s *SQLiteStore) DeactivateUnseen(ctx context.Context, category string, seenIDs []string) (int64, error) {
+	now := sqlTime(s.now())
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE listings
+		SET is_active = 0, delisted_at = ?, updated_at = ?
+		WHERE is_active = 1
+		  AND category = ?
+		  AND external_id NOT IN (SELECT value FROM json_each(?))`,
+		now, now, category, jsonText(seenIDs),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("deactivate unseen %s listings: %w", category, err)
+	}
+	return res.RowsAffected()
+}
+
+// SetDealScores has the semantics of the package-level SetDealScores.
+func (s *SQLiteStore) SetDealScores(ctx context.Context, scores []models.DealScore) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE listings
+			SET expected_price = NULL, deal_score = NULL, deal_label = NULL
+			WHERE deal_score IS NOT NULL`)
+		if err != nil {
+			return fmt.Errorf("clear deal scores: %w", err)
+		}
+		for _, sc := range scores {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE listings
+				SET expected_price = ?, deal_score = ?, deal_label = ?
+				WHERE id = ?`,
+				sc.ExpectedPrice, sc.Score, sc.Label, sc.ListingID,
+			)
+			if err != nil {
+				return fmt.Errorf("set deal score %s: %w", sc.ListingID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("set deal scores: %w", err)
+	}
+	return nil
+}
+
+// ── Stats ──
+
+// GetStats mirrors the package-level GetStats. SQLite has no PERCENTILE_CONT,
+// so the median is computed in Go from the matching prices.
+func (s *SQLiteStore) GetStats(ctx context.Context, category string) (models.Stats, error) {
+	stats := models.Stats{
+		TopBrands:        make([]models.BrandStat, 0),
+		BodyTypes:        make([]models.BodyTypeStat, 0),
+		YearDistribution: make([]models.YearStat, 0),
+	}
+	weekAgo := sqlTime(s.now().Add(-7 * 24 * time.Hour))
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COALESCE(AVG(price), 0),
+			COUNT(*) FILTER (WHERE first_seen >= ?2),
+			COALESCE(AVG(mileage), 0)
+		FROM listings
+		WHERE is_active = 1 AND (?1 = '' OR category = ?1)
+	`, category, weekAgo).Scan(&stats.TotalListings, &stats.AvgPrice, &stats.NewThisWeek, &stats.AvgMileage)
+	if err != nil {
+		return stats, fmt.Errorf("get stats aggregate: %w", err)
+	}
+
+	prices, err := s.db.QueryContext(ctx, `
+		SELECT price FROM listings
+		WHERE is_active = 1 AND (?1 = '' OR category = ?1) AND price IS NOT NULL
+	`, category)
+	if err != nil {
+		return stats, fmt.Errorf("get median price: %w", err)
+	}
+	var vals []float64
+	if err := collectRows(prices, func(r *sql.Rows) error {
+		var p float64
+		err := r.Scan(&p)
+		vals = append(vals, p)
+		return err
+	}); err != nil {
+		return stats, err
+	}
+	stats.MedianPrice = median(vals)
+
+	brands, err := s.db.QueryContext(ctx, `
+		SELECT make, COUNT(*), COALESCE(AVG(price), 0)
+		FROM listings
+		WHERE is_active = 1 AND (?1 = '' OR category = ?1) AND make IS NOT NULL AND make != ''
+		GROUP BY make
+		ORDER BY COUNT(*) DESC, make
+		LIMIT 8
+	`, category)
+	if err != nil {
+		return stats, fmt.Errorf("get top brands: %w", err)
+	}
+	if err := collectRows(brands, func(r *sql.Rows) error {
+		var b models.BrandStat
+		err := r.Scan(&b.Name, &b.Count, &b.AvgPrice)
+		stats.TopBrands = append(stats.TopBrands, b)
+		return err
+	}); err != nil {
+		return stats, err
+	}
+
+	bodyTypes, err := s.db.QueryContext(ctx, `
+		SELECT
+			COALESCE(NULLIF(TRIM(body_type), ''), 'Other') AS bt,
+			COUNT(*),
+			COALESCE(AVG(price), 0)
+		FROM listings
+		WHERE is_active = 1 AND (?1 = '' OR category = ?1)
+		GROUP BY bt
+		ORDER BY COUNT(*) DESC, bt
+	`, category)
+	if err != nil {
+		return stats, fmt.Errorf("get body types: %w", err)
+	}
+	if err := collectRows(bodyTypes, func(r *sql.Rows) error {
+		var t models.BodyTypeStat
+		err := r.Scan(&t.Type, &t.Count, &t.AvgPrice)
+		stats.BodyTypes = append(stats.BodyTypes, t)
+		return err
+	}); err != nil {
+		return stats, err
+	}
+
+	years, err := s.db.QueryContext(ctx, `
+		SELECT year, COUNT(*)
+		FROM listings
+		WHERE is_active = 1 AND (?1 = '' OR category = ?1) AND year IS NOT NULL
+		GROUP BY year
+		ORDER BY year
+	`, category)
+	if err != nil {
+		return stats, fmt.Errorf("get year distribution: %w", err)
+	}
+	if err := collectRows(years, func(r *sql.Rows) error {
+		var y models.YearStat
+		err := r.Scan(&y.Year, &y.Count)
+		stats.YearDistribution = append(stats.YearDistribution, y)
+		return err
+	}); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// ── Trends ──
+
+// MaterializeDailyInventory has the semantics of the package-level
+// MaterializeDailyInventory.
+func (s *SQLiteStore) MaterializeDailyInventory(ctx context.Context) (int64, error) {
+	var written int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var from *string
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(
+				(SELECT MAX(day) FROM daily_inventory),
+				(SELECT date(MIN(first_seen)) FROM listings)
+			)`).Scan(&from)
+		if err != nil {
+			return fmt.Errorf("find daily inventory start: %w", err)
+		}
+		if from == nil {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM daily_inventory WHERE day >= ?`, *from); err != nil {
+			return fmt.Errorf("clear daily inventory: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, `
+			WITH RECURSIVE days(day) AS (
+				SELECT ?1
+				UNION ALL
+				SELECT date(day, '+1 day') FROM days WHERE day < ?2
+			)
+			INSERT INTO daily_inventory (day, listing_id, category, make, body_type, price)
+			SELECT d.day, l.id, l.category, l.make, l.body_type,
+				COALESCE((
+					SELECT ph.price FROM price_history ph
+					WHERE ph.listing_id = l.id AND ph.recorded_at < date(d.day, '+1 day')
+					ORDER BY ph.recorded_at DESC
+					LIMIT 1
+				), l.price)
+			FROM days d
+			JOIN listings l
+			  ON l.first_seen < date(d.day, '+1 day')
+			 AND COALESCE(l.delisted_at, CASE WHEN l.is_active THEN NULL ELSE l.last_seen END, '9999') >= d.day`,
+			*from, s.now().UTC().Format(dayLayout),
+		)
+		if err != nil {
+			return fmt.Errorf("materialize daily inventory: %w", err)
+		}
+		written, err = res.RowsAffected()
+		return err
+	})
+	return written, err
+}
+
+// GetTrends mirrors the package-level GetTrends, with the bucketing and
+// medians done in Go.
+func (s *SQLiteStore) GetTrends(ctx context.Context, f TrendFilter) ([]models.TrendBucket, error) {
+	set := newTrendSet(f.Granularity)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT day, COALESCE(price, 0)
+		FROM daily_inventory
+		WHERE (?1 = '' OR category = ?1)
+		  AND (?2 = '' OR LOWER(make) = LOWER(?2))
+		  AND (?3 = '' OR LOWER(body_type) = LOWER(?3))
+	`, f.Category, f.Make, f.BodyType)
+	if err != nil {
+		return nil, fmt.Errorf("query inventory trends: %w", err)
+	}
+	err = collectRows(rows, func(r *sql.Rows) error {
+		var (
+			day   string
+			price float64
+		)
+		if err := r.Scan(&day, &price); err != nil {
+			return fmt.Errorf("scan inventory trend row: %w", err)
+		}
+		d, err := time.Parse(dayLayout, day)
+		if err != nil {
+			return fmt.Errorf("parse inventory day %q: %w", day, err)
+		}
+		set.addLive(d, price)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT first_seen, delisted_at
+		FROM listings
+		WHERE (?1 = '' OR category = ?1)
+		  AND (?2 = '' OR LOWER(make) = LOWER(?2))
+		  AND (?3 = '' OR LOWER(body_type) = LOWER(?3))
+	`, f.Category, f.Make, f.BodyType)
+	if err != nil {
+		return nil, fmt.Errorf("query listing flow trends: %w", err)
+	}
+	err = collectRows(rows, func(r *sql.Rows) error {
+		var firstSeen_, delistedAt_ *string
+		if err := r.Scan(&firstSeen_, &delistedAt_); err != nil {
+			return fmt.Errorf("scan listing flow trend row: %w", err)
+		}
+		firstSeen, err := parseSQLTimePtr(firstSeen_)
+		if err != nil {
+			return err
+		}
+		delistedAt, err := parseSQLTimePtr(delistedAt_)
+		if err != nil {
+			return err
+		}
+		if firstSeen != nil {
+			set.at(*firstSeen).New++
+		}
+		if delistedAt != nil {
+			set.at(*delistedAt).Delisted++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set.list(), nil
+}
+
+// ── Sellers ──
+
+func (s *SQLiteStore) UpsertSellers(ctx context.Context, sellers []models.Seller) error {
+	now := sqlTime(s.now())
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, seller := range sellers {
+			var stored string
+			err := tx.QueryRowContext(ctx,
+				`SELECT profile_markers FROM sellers WHERE profile_url = ?`, seller.ProfileURL,
+			).Scan(&stored)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("upsert seller %s: %w", seller.ProfileURL, err)
+			}
+			var markers []string
+			if stored != "" {
+				_ = json.Unmarshal([]byte(stored), &markers)
+			}
+			for _, m := range seller.ProfileMarkers {
+				if !slices.Contains(markers, m) {
+					markers = append(markers, m)
+				}
+			}
+			if markers == nil {
+				markers = []string{}
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO sellers (id, profile_url, name, profile_markers, first_seen, last_seen)
+				VALUES (?, ?, NULLIF(?, ''), ?, ?, ?)
+				ON CONFLICT (profile_url) DO UPDATE SET
+					name            = COALESCE(excluded.name, sellers.name),
+					profile_markers = excluded.profile_markers,
+					last_seen       = excluded.last_seen`,
+				newUUID(), seller.ProfileURL, seller.Name, jsonText(markers), now, now,
+			)
+			if err != nil {
+				return fmt.Errorf("upsert seller %s: %w", seller.ProfileURL, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) SetSellerType(ctx context.Context, profileURL, sellerType string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sellers SET seller_type = ? WHERE profile_url = ?`, sellerType, profileURL,
+	)
+	if err != nil {
+		return fmt.Errorf("set seller type %s: %w", profileURL, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetSellers(ctx context.Context, sellerType string) ([]models.Seller, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			s.id, s.profile_url, COALESCE(s.name, ''), s.seller_type, s.profile_markers,
+			COUNT(l.id),
+			COUNT(l.id) FILTER (WHERE l.is_active = 1),
+			COALESCE(AVG(l.price) FILTER (WHERE l.is_active = 1), 0),
+			COALESCE(AVG(
+				julianday(COALESCE(l.delisted_at, CASE WHEN l.is_active = 1 THEN ?2 ELSE l.last_seen END))
+				- julianday(COALESCE(l.posted_at, l.first_seen))
+			), 0),
+			s.first_seen, s.last_seen
+		FROM sellers s
+		LEFT JOIN listings l ON l.seller_url = s.profile_url
+		WHERE ?1 = '' OR s.seller_type = ?1
+		GROUP BY s.id
+		ORDER BY COUNT(l.id) FILTER (WHERE l.is_active = 1) DESC, s.name
+	`, sellerType, sqlTime(s.now()))
+	if err != nil {
+		return nil, fmt.Errorf("query sellers: %w", err)
+	}
+
+	var sellers []models.Seller
+	err = collectRows(rows, func(r *sql.Rows) error {
+		var (
+			seller              models.Seller
+			markers             string
+			firstSeen, lastSeen string
+		)
+		err := r.Scan(
+			&seller.ID, &seller.ProfileURL, &seller.Name, &seller.SellerType, &markers,
+			&seller.ListingCount, &seller.InventoryCount, &seller.AvgPrice, &seller.AvgDaysOnMarket,
+			&firstSeen, &lastSeen,
+		)
+		if err != nil {
+			return fmt.Errorf("scan seller row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(markers), &seller.ProfileMarkers); err != nil {
+			return fmt.Errorf("unmarshal profile markers %s: %w", seller.ProfileURL, err)
+		}
+		if seller.FirstSeen, err = parseSQLTimePtr(&firstSeen); err != nil {
+			return err
+		}
+		if seller.LastSeen, err = parseSQLTimePtr(&lastSeen); err != nil {
+			return err
+		}
+		sellers = append(sellers, seller)
+		return nil
+	})
+	return sellers, err
+}
+
+// ── Scrape runs and snapshots ──
+
+func (s *SQLiteStore) StartScrapeRun(ctx context.Context, run models.ScrapeRun) (string, error) {
+	id := newUUID()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO scrape_runs (id, started_at, status) VALUES (?, ?, ?)`,
+		id, sqlTime(run.StartedAt), run.Status,
+	)
+	if err != nil {
+		return "", fmt.Errorf("insert scrape run: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SQLiteStore) FinishScrapeRun(ctx context.Context, id string, run models.ScrapeRun) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE scrape_runs SET
+			finished_at = ?, status = ?, complete = ?,
+			pages_visited = ?, raw_cards = ?, accepted = ?, unchanged = ?,
+			skipped_empty = ?, skipped_no_year = ?, skipped_low_price = ?, skipped_price_on_request = ?,
+			detail_failures = ?, blocked_pages = ?, enrich_calls = ?, enrich_failures = ?,
+			inserted = ?, updated = ?, price_changed = ?, upsert_errors = ?,
+			delisted = ?, error = NULLIF(?, '')
+		WHERE id = ?`,
+		sqlTimePtr(run.FinishedAt), run.Status, run.Complete,
+		run.PagesVisited, run.RawCards, run.Accepted, run.Unchanged,
+		run.SkippedEmpty, run.SkippedNoYear, run.SkippedLowPrice, run.SkippedPriceOnRequest,
+		run.DetailFailures, run.BlockedPages, run.EnrichCalls, run.EnrichFailures,
+		run.Inserted, run.Updated, run.PriceChanged, run.UpsertErrors,
+		run.Delisted, run.Error, id,
+	)
+	if err != nil {
+		return fmt.Errorf("finish scrape run %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetScrapeRuns(ctx context.Context, limit int) ([]models.ScrapeRun, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			id, started_at, finished_at, status, complete,
+			pages_visited, raw_cards, accepted, unchanged,
+			skipped_empty, skipped_no_year, skipped_low_price, skipped_price_on_request,
+			detail_failures, blocked_pages, enrich_calls, enrich_failures,
+			inserted, updated, price_changed, upsert_errors,
+			delisted, COALESCE(error, '')
+		FROM scrape_runs
+		ORDER BY started_at DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query scrape runs: %w", err)
+	}
+
+	var runs []models.ScrapeRun
+	err = collectRows(rows, func(r *sql.Rows) error {
+		var (
+			run        models.ScrapeRun
+			startedAt  string
+			finishedAt *string
+		)
+		err := r.Scan(
+			&run.ID, &startedAt, &finishedAt, &run.Status, &run.Complete,
+			&run.PagesVisited, &run.RawCards, &run.Accepted, &run.Unchanged,
+			&run.SkippedEmpty, &run.SkippedNoYear, &run.SkippedLowPrice, &run.SkippedPriceOnRequest,
+			&run.DetailFailures, &run.BlockedPages, &run.EnrichCalls, &run.EnrichFailures,
+			&run.Inserted, &run.Updated, &run.PriceChanged, &run.UpsertErrors,
+			&run.Delisted, &run.Error,
+		)
+		if err != nil {
+			return fmt.Errorf("scan scrape run row: %w", err)
+		}
+		if run.StartedAt, err = parseSQLTime(startedAt); err != nil {
+			return err
+		}
+		if run.FinishedAt, err = parseSQLTimePtr(finishedAt); err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		return nil
+	})
+	return runs, err
+}
+
+func (s *SQLiteStore) InsertListingSnapshot(ctx context.Context, runID string, snap models.ListingSnapshot) error {
+	html, err := gzipString(snap.DetailHTML)
+	if err != nil {
+		return fmt.Errorf("compress snapshot %s: %w", snap.ExternalID, err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO listing_snapshots
+			(id, run_id, external_id, category, url, card_text, img_src,
+			 detail_html_gz, detail_text, detail_fields, detail_images, captured_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?, ?)`,
+		newUUID(), runID, snap.ExternalID, categoryOrDefault(snap.Category), snap.URL, snap.CardText, snap.ImgSrc,
+		html, snap.DetailText, jsonText(snap.DetailFields), jsonText(snap.DetailImages), sqlTime(s.now()),
+	)
+	if err != nil {
+		return fmt.Errorf("insert snapshot %s: %w", snap.ExternalID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) PruneListingSnapshots(ctx context.Context, retention time.Duration) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM listing_snapshots AS s
+		WHERE s.captured_at < ?
+		  AND EXISTS (
+			SELECT 1 FROM listing_snapshots n
+			WHERE n.external_id = s.external_id
+			  AND n.captured_at > s.captured_at
+		  )`,
+		sqlTime(s.now().Add(-retention)),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("prune listing snapshots: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ── Scanning and encoding ──
+
+// collectSQLiteListings scans every row selected with listingColumns and
+// closes rows.
+func collectSQLiteListings(rows *sql.Rows) ([]models.Listing, error) {
+	var listings []models.Listing
+	err := collectRows(rows, func(r *sql.Rows) error {
+		l, err := scanSQLiteListing(r)
+		listings = append(listings, l)
+		return err
+	})
+	return listings, err
+}
+
+// scanSQLiteListing scans a single row selected with listingColumns.
+func scanSQLiteListing(row *sql.Rows) (models.Listing, error) {
+	var (
+		l models.Listing
+		// Nullable text columns.
+		make_, model_, condition_, transmission_ *string
+		fuelType_, color_, bodyType_, drive_     *string
+		cylinders_, steering_, interiorColor_    *string
+		doors_, description_, location_          *string
+		sellerName_, sellerURL_, sellerType_     *string
+		currency_, attributes_, images_          *string
+		dealLabel_                               *string
+		price_                                   *float64
+		// Timestamp columns, as sqliteTimeLayout text.
+		postedAt_, adUpdatedAt_                                    *string
+		firstSeen_, lastSeen_, delistedAt_, createdAt_, updatedAt_ *string
+	)
+
+	err := row.Scan(
+		&l.ID, &l.ExternalID, &l.Category, &l.URL, &l.Title,
+		&make_, &model_, &l.Year, &l.Mileage,
+		&price_, &currency_, &condition_, &transmission_,
+		&fuelType_, &color_, &bodyType_, &drive_,
+		&cylinders_, &steering_, &interiorColor_, &doors_, &l.OnIsland,
+		&attributes_, &description_, &images_,
+		&location_, &sellerName_, &sellerURL_, &sellerType_, &postedAt_, &adUpdatedAt_, &l.IsActive,
+		&firstSeen_, &lastSeen_, &delistedAt_, &createdAt_, &updatedAt_,
+		&l.ExpectedPrice, &l.DealScore, &dealLabel_,
+	)
+	if err != nil {
+		return l, fmt.Errorf("scan listing row: %w", err)
+	}
+	if attributes_ != nil {
+		if err := json.Unmarshal([]byte(*attributes_), &l.Attributes); err != nil {
+			return l, fmt.Errorf("unmarshal attributes %s: %w", l.ExternalID, err)
+		}
+	}
+	if images_ != nil {
+		if err := json.Unmarshal([]byte(*images_), &l.Images); err != nil {
+			return l, fmt.Errorf("unmarshal images %s: %w", l.ExternalID, err)
+		}
+	}
+	if price_ != nil {
+		l.Price = *price_
+	}
+
+	l.Make = strVal(make_)
+	l.Model = strVal(model_)
+	l.Currency = strVal(currency_)
+	l.Condition = strVal(condition_)
+	l.Transmission = strVal(transmission_)
+	l.FuelType = strVal(fuelType_)
+	l.Color = strVal(color_)
+	l.BodyType = strVal(bodyType_)
+	l.Drive = strVal(drive_)
+	l.Cylinders = strVal(cylinders_)
+	l.Steering = strVal(steering_)
+	l.InteriorColor = strVal(interiorColor_)
+	l.Doors = strVal(doors_)
+	l.Description = strVal(description_)
+	l.Location = strVal(location_)
+	l.SellerName = strVal(sellerName_)
+	l.SellerURL = strVal(sellerURL_)
+	l.SellerType = strVal(sellerType_)
+	l.DealLabel = strVal(dealLabel_)
+
+	for _, t := range []struct {
+		src *string
+		dst **time.Time
+	}{
+		{postedAt_, &l.PostedAt}, {adUpdatedAt_, &l.AdUpdatedAt},
+		{firstSeen_, &l.FirstSeen}, {lastSeen_, &l.LastSeen}, {delistedAt_, &l.DelistedAt},
+		{createdAt_, &l.CreatedAt}, {updatedAt_, &l.UpdatedAt},
+	} {
+		if *t.dst, err = parseSQLTimePtr(t.src); err != nil {
+			return l, err
+		}
+	}
+	return l, nil
+}
+
+// collectRows calls scan for every row and closes rows.
+func collectRows(rows *sql.Rows, scan func(*sql.Rows) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+// sqlTime formats t for a SQLiteStore timestamp column.
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqlTimePtr formats t, or returns nil (SQL NULL) for a nil pointer.
+func sqlTimePtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqlTime(*t)
+}
+
+// parseSQLTime parses a SQLiteStore timestamp column.
+func parseSQLTime(s string) (time.Time, error) {
+	t, err := time.Parse(sqliteTimeLayout, s)
+	if err != nil {
+		return t, fmt.Errorf("parse sqlite timestamp %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// parseSQLTimePtr parses a nullable timestamp column, returning nil for NULL.
+func parseSQLTimePtr(s *string) (*time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	t, err := parseSQLTime(*s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// jsonText encodes v for a JSON text column, or returns nil (SQL NULL) when v
+// is a nil slice or map.
+func jsonText[T any](v T) any {
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil
+	}
+	return string(b)
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/config"
+	"ecaycar/backend/models"
+)
+
+// ListingStore is the listing storage the API serves from. PgStore is the
+// Postgres implementation and SQLiteStore the local-development one;
+// MemoryStore keeps everything in memory so handlers can be exercised without
+// a database.
+type ListingStore interface {
+	// Ping reports whether the store is reachable.
+	Ping(ctx context.Context) error
+
+	// UpsertListings writes a scrape run's listings and their history rows
+	// all-or-nothing. See the package-level UpsertListings.
+	UpsertListings(ctx context.Context, listings []models.Listing) ([]UpsertResult, error)
+
+	// GetListings returns one page of the active listings matching f and
+	// the total number of matches.
+	GetListings(ctx context.Context, f ListingFilter) ([]models.Listing, int, error)
+
+	// GetListing returns one listing, active or not, by UUID or external_id.
+	// An unknown listing yields ErrListingNotFound.
+	GetListing(ctx context.Context, id string) (models.Listing, error)
+
+	// GetStats returns dashboard statistics, limited to category when it is
+	// non-empty.
+	GetStats(ctx context.Context, category string) (models.Stats, error)
+
+	// GetListingChanges returns a listing's field-level changes, newest
+	// first. An unknown listing yields ErrListingNotFound.
+	GetListingChanges(ctx context.Context, id string) ([]models.ListingChange, error)
+
+	// GetPriceHistory returns a listing's recorded asking prices, oldest
+	// first. An unknown listing yields ErrListingNotFound.
+	GetPriceHistory(ctx context.Context, id string) ([]models.PricePoint, error)
+
+	// GetSellers returns sellers with their inventory figures, limited to
+	// sellerType when it is non-empty.
+	GetSellers(ctx context.Context, sellerType string) ([]models.Seller, error)
+
+	// GetScrapeRuns returns the most recent scrape runs, newest first.
+	GetScrapeRuns(ctx context.Context, limit int) ([]models.ScrapeRun, error)
+
+	// GetTrends returns market figures per f.Granularity bucket, oldest
+	// first.
+	GetTrends(ctx context.Context, f TrendFilter) ([]models.TrendBucket, error)
+}
+
+// ScrapeStore is everything cmd/scraper writes through: the ListingStore plus
+// the run ledger, snapshot archive, seller table, deal scores, daily
+// inventory and delisting sweep. PgStore and SQLiteStore implement it.
+type ScrapeStore interface {
+	ListingStore
+
+	GetKnownListings(ctx context.Context) (map[string]models.KnownListing, error)
+	TouchListings(ctx context.Context, externalIDs []string) (int64, error)
+	CountActiveListings(ctx context.Context, category string) (int, error)
+	DeactivateUnseen(ctx context.Context, category string, seenIDs []string) (int64, error)
+
+	UpsertSellers(ctx context.Context, sellers []models.Seller) error
+	SetSellerType(ctx context.Context, profileURL, sellerType string) error
+
+	SetDealScores(ctx context.Context, scores []models.DealScore) error
+	MaterializeDailyInventory(ctx context.Context) (int64, error)
+
+	StartScrapeRun(ctx context.Context, run models.ScrapeRun) (string, error)
+	FinishScrapeRun(ctx context.Context, id string, run models.ScrapeRun) error
+
+	InsertListingSnapshot(ctx context.Context, runID string, s models.ListingSnapshot) error
+	PruneListingSnapshots(ctx context.Context, retention time.Duration) (int64, error)
+
+	// Close releases the store's connections.
+	Close()
+}
+
+// sqliteScheme prefixes DATABASE_URL values that select SQLiteStore.
+const sqliteScheme = "sqlite://"
+
+// OpenStore opens the store named by cfg.DatabaseURL. A sqlite:// URL, e.g.
+// sqlite://./ecay.db, opens (creating and migrating if needed) a local SQLite
+// file; anything else connects to Postgres with InitDB and checks its
+// migrations with CheckSchema.
+func OpenStore(ctx context.Context, cfg *config.Config) (ScrapeStore, error) {
+	if path, ok := strings.CutPrefix(cfg.DatabaseURL, sqliteScheme); ok {
+		return OpenSQLite(ctx, path)
+	}
+
+	pool, err := InitDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSchema(ctx, pool, cfg); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return NewPgStore(pool), nil
+}
+
+// PgStore is the Postgres ScrapeStore. Its methods delegate to the
+// package-level query functions.
+type PgStore struct {
+	pool *pgxpool.Pool
+}
+
+var _ ScrapeStore = (*PgStore)(nil)
+
+// NewPgStore returns a PgStore backed by pool.
+func NewPgStore(pool *pgxpool.Pool) *PgStore {
+	return &PgStore{pool: pool}
+}
+
+func (s *PgStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *PgStore) UpsertListings(ctx context.Context, listings []models.Listing) ([]UpsertResult, error) {
+	return UpsertListings(ctx, s.pool, listings)
+}
+
+func (s *PgStore) GetListings(ctx context.Context, f ListingFilter) ([]models.Listing, int, error) {
+	return GetListings(ctx, s.pool, f)
+}
+
+func (s *PgStore) GetListing(ctx context.Context, id string) (models.Listing, error) {
+	return GetListing(ctx, s.pool, id)
+}
+
+func (s *PgStore) GetStats(ctx context.Context, category string) (models.Stats, error) {
+	return GetStats(ctx, s.pool, category)
+}
+
+func (s *PgStore) GetListingChanges(ctx context.Context, id string) ([]models.ListingChange, error) {
+	return GetListingChanges(ctx, s.pool, id)
+}
+
+func (s *PgStore) GetPriceHistory(ctx context.Context, id string) ([]models.PricePoint, error) {
+	return GetPriceHistory(ctx, s.pool, id)
+}
+
+func (s *PgStore) GetSellers(ctx context.Context, sellerType string) ([]models.Seller, error) {
+	return GetSellers(ctx, s.pool, sellerType)
+}
+
+func (s *PgStore) GetScrapeRuns(ctx context.Context, limit int) ([]models.ScrapeRun, error) {
+	return GetScrapeRuns(ctx, s.pool, limit)
+}
+
+func (s *PgStore) GetTrends(ctx context.Context, f TrendFilter) ([]models.TrendBucket, error) {
+	return GetTrends(ctx, s.pool, f)
+}
+
+func (s *PgStore) GetKnownListings(ctx context.Context) (map[string]models.KnownListing, error) {
+	return GetKnownListings(ctx, s.pool)
+}
+
+func (s *PgStore) TouchListings(ctx context.Context, externalIDs []string) (int64, error) {
+	return TouchListings(ctx, s.pool, externalIDs)
+}
+
+func (s *PgStore) CountActiveListings(ctx context.Context, category string) (int, error) {
+	return CountActiveListings(ctx, s.pool, category)
+}
+
+func (s *PgStore) DeactivateUnseen(ctx context.Context, category string, seenIDs []string) (int64, error) {
+	return DeactivateUnseen(ctx, s.pool, category, seenIDs)
+}
+
+func (s *PgStore) UpsertSellers(ctx context.Context, sellers []models.Seller) error {
+	return UpsertSellers(ctx, s.pool, sellers)
+}
+
+func (s *PgStore) SetSellerType(ctx context.Context, profileURL, sellerType string) error {
+	return SetSellerType(ctx, s.pool, profileURL, sellerType)
+}
+
+func (s *PgStore) SetDealScores(ctx context.Context, scores []models.DealScore) error {
+	return SetDealScores(ctx, s.pool, scores)
+}
+
+func (s *PgStore) MaterializeDailyInventory(ctx context.Context) (int64, error) {
+	return MaterializeDailyInventory(ctx, s.pool)
+}
+
+func (s *PgStore) StartScrapeRun(ctx context.Context, run models.ScrapeRun) (string, error) {
+	return StartScrapeRun(ctx, s.pool, run)
+}
+
+func (s *PgStore) FinishScrapeRun(ctx context.Context, id string, run models.ScrapeRun) error {
+	return FinishScrapeRun(ctx, s.pool, id, run)
+}
+
+func (s *PgStore) InsertListingSnapshot(ctx context.Context, runID string, snap models.ListingSnapshot) error {
+	return InsertListingSnapshot(ctx, s.pool, runID, snap)
+}
+
+func (s *PgStore) PruneListingSnapshots(ctx context.Context, retention time.Duration) (int64, error) {
+	return PruneListingSnapshots(ctx, s.pool, retention)
+}
+
+func (s *PgStore) Close() {
+	s.pool.Close()
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// historyStore is the part of a store the parity test drives.
+type historyStore interface {
+	UpsertListings(ctx context.Context, listings []models.Listing) ([]UpsertResult, error)
+	GetListing(ctx context.Context, id string) (models.Listing, error)
+	GetPriceHistory(ctx context.Context, id string) ([]models.PricePoint, error)
+	GetListingChanges(ctx context.Context, id string) ([]models.ListingChange, error)
+}
+
+// TestUpsertHistoryParity runs the same scrape runs through MemoryStore and
+// SQLiteStore and checks they report and record the same history.
+func TestUpsertHistoryParity(t *testing.T) {
+	ctx := context.Background()
+	year, miles, rolledBack := 2018, 52000, 41000
+
+	camry := models.Listing{
+		ExternalID: "100", URL: "https://ecaytrade.com/advert/100", Title: "2018 Toyota Camry SE",
+		Make: "Toyota", Model: "Camry", Year: &year, Mileage: &miles, Price: 15000, Currency: "CI$",
+		Transmission: "Automatic", BodyType: "Sedan", Color: "Silver",
+		Images:      []string{"https://img/100-1.jpg", "https://img/100-2.jpg"},
+		Description: "One owner", SellerName: "Island Motors",
+	}
+	civic := models.Listing{
+		ExternalID: "200", URL: "https://ecaytrade.com/advert/200", Title: "2015 Honda Civic",
+		Make: "Honda", Model: "Civic", Price: 8000, Currency: "CI$",
+	}
+
+	rerun := camry
+	rerun.Price, rerun.Mileage, rerun.Title = 14000, &rolledBack, "2018 Toyota Camry SE (new tyres)"
+
+	// The detail fetch failed: only card-level fields, and the card thumbnail.
+	cardOnly := rerun
+	cardOnly.CardOnly = true
+	cardOnly.Mileage, cardOnly.Transmission, cardOnly.BodyType, cardOnly.Color = nil, "", "", ""
+	cardOnly.Description, cardOnly.SellerName = "", ""
+	cardOnly.Images = []string{"https://img/100-thumb.jpg"}
+
+	runs := [][]models.Listing{
+		{camry, civic},
+		{rerun, civic},
+		{cardOnly},
+		{rerun},
+	}
+
+	sq, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "parity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sq.Close()
+	mem := NewMemoryStore()
+
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+	sq.now, mem.now = now, now
+
+	stores := map[string]historyStore{"memory": mem, "sqlite": sq}
+	for i, run := range runs {
+		clock = clock.Add(time.Hour)
+		var want []UpsertResult
+		for _, name := range []string{"memory", "sqlite"} {
+			got, err := stores[name].UpsertListings(ctx, run)
+			if err != nil {
+				t.Fatalf("run %d: %s: %v", i, name, err)
+			}
+			if want == nil {
+				want = got
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("run %d: sqlite results %+v, memory %+v", i, got, want)
+			}
+		}
+		if i == 2 && (len(want[0].Changes) != 0 || want[0].PriceChanged) {
+			t.Errorf("card-only run recorded changes: %+v", want[0])
+		}
+	}
+
+	for _, id := range []string{"100", "200"} {
+		var wantPrices []models.PricePoint
+		var wantChanges []models.ListingChange
+		var wantListing models.Listing
+		for _, name := range []string{"memory", "sqlite"} {
+			s := stores[name]
+			prices, err := s.GetPriceHistory(ctx, id)
+			if err != nil {
+				t.Fatalf("%s %s price history: %v", name, id, err)
+			}
+			changes, err := s.GetListingChanges(ctx, id)
+			if err != nil {
+				t.Fatalf("%s %s changes: %v", name, id, err)
+			}
+			l, err := s.GetListing(ctx, id)
+			if err != nil {
+				t.Fatalf("%s %s listing: %v", name, id, err)
+			}
+			if name == "memory" {
+				wantPrices, wantChanges, wantListing = prices, changes, l
+				continue
+			}
+			if !pricePointsEqual(prices, wantPrices) {
+				t.Errorf("%s price history: sqlite %+v, memory %+v", id, prices, wantPrices)
+			}
+			if !changesEqual(changes, wantChanges) {
+				t.Errorf("%s changes: sqlite %+v, memory %+v", id, changes, wantChanges)
+			}
+			if !reflect.DeepEqual(l.Images, wantListing.Images) || l.Transmission != wantListing.Transmission ||
+				l.Description != wantListing.Description || !reflect.DeepEqual(l.Mileage, wantListing.Mileage) {
+				t.Errorf("%s stored listing: sqlite %+v, memory %+v", id, l, wantListing)
+			}
+		}
+	}
+
+	// Spot-check the shared history against what the runs should produce.
+	prices, _ := mem.GetPriceHistory(ctx, "100")
+	if len(prices) != 2 || prices[0].Price != 15000 || prices[1].Price != 14000 {
+		t.Errorf("price history = %+v, want 15000 then 14000", prices)
+	}
+	changes, _ := mem.GetListingChanges(ctx, "100")
+	fields := map[string]int{}
+	for _, c := range changes {
+		fields[c.Field]++
+	}
+	if want := map[string]int{"title": 1, "mileage": 1, "price": 1}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("changed fields = %v, want %v", fields, want)
+	}
+	l, _ := mem.GetListing(ctx, "100")
+	if len(l.Images) != 2 || l.Transmission != "Automatic" || l.Description != "One owner" {
+		t.Errorf("card-only run overwrote details: images %v, transmission %q, description %q",
+			l.Images, l.Transmission, l.Description)
+	}
+}
+
+func pricePointsEqual(a, b []models.PricePoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Price != b[i].Price || !a[i].RecordedAt.Equal(b[i].RecordedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+func changesEqual(a, b []models.ListingChange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].FieldChange != b[i].FieldChange || !a[i].ChangedAt.Equal(b[i].ChangedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestKeepAdDate checks that relative ad dates re-resolved on a later crawl
+// do not move the stored date forward, while a genuine renewal does.
+func TestKeepAdDate(t *testing.T) {
+	day := 24 * time.Hour
+	stored := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { v := stored.Add(d); return &v }
+
+	tests := []struct {
+		name     string
+		fresh    *time.Time
+		slack    time.Duration
+		earliest bool
+		want     *time.Time
+	}{
+		{"no fresh value", nil, 0, false, &stored},
+		{"same week, re-resolved later", at(3 * day), 7 * day, false, &stored},
+		{"renewed beyond the unit", at(10 * day), 7 * day, false, at(10 * day)},
+		{"absolute date changed", at(day), 0, false, at(day)},
+		{"fresh is earlier", at(-2 * day), 7 * day, false, at(-2 * day)},
+		{"posting date keeps earliest", at(40 * day), 31 * day, true, &stored},
+		{"posting date moves earlier", at(-day), day, true, at(-day)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keepAdDate(&stored, tt.fresh, tt.slack, tt.earliest)
+			if got == nil || !got.Equal(*tt.want) {
+				t.Errorf("keepAdDate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRelativeAdDatesNoChanges re-crawls a listing whose dates are shown
+// relative to the crawl and checks listing_changes stays empty until the ad
+// is actually renewed.
+func TestRelativeAdDatesNoChanges(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemoryStore()
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mem.now = func() time.Time { return clock }
+
+	// "Posted 3 weeks ago", "Updated 2 days ago", as seen at crawl time.
+	crawl := func(posted, updated time.Time) models.Listing {
+		return models.Listing{
+			ExternalID: "300", URL: "https://ecaytrade.com/advert/300", Title: "2016 Mazda 3",
+			Price: 9000, Currency: "CI$",
+			PostedAt: &posted, PostedSlack: 7 * 24 * time.Hour,
+			AdUpdatedAt: &updated, UpdatedSlack: 24 * time.Hour,
+		}
+	}
+	first := clock
+	if _, err := mem.UpsertListings(ctx, []models.Listing{crawl(first.AddDate(0, 0, -21), first.AddDate(0, 0, -2))}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Six hours later the page still says the same thing.
+	clock = clock.Add(6 * time.Hour)
+	res, err := mem.UpsertListings(ctx, []models.Listing{crawl(clock.AddDate(0, 0, -21), clock.AddDate(0, 0, -2))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res[0].Changes) != 0 {
+		t.Errorf("re-crawl recorded changes: %+v", res[0].Changes)
+	}
+
+	// Renewed: "Updated 1 hour ago".
+	res, err = mem.UpsertListings(ctx, []models.Listing{crawl(clock.AddDate(0, 0, -21), clock.Add(-time.Hour))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res[0].Changes) != 1 || res[0].Changes[0].Field != "ad_updated_at" {
+		t.Errorf("renewal changes = %+v, want only ad_updated_at", res[0].Changes)
+	}
+	l, _ := mem.GetListing(ctx, "300")
+	if want := first.AddDate(0, 0, -21); !l.PostedAt.Equal(want) {
+		t.Errorf("posted_at = %v, want %v", l.PostedAt, want)
+	}
+}
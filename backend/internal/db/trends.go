@@ -0,0 +1,251 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// Trend granularities accepted by TrendFilter.Granularity. Weeks start on
+// Monday; every bucket is in UTC.
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// dayLayout formats daily_inventory days and TrendBucket.Bucket.
+const dayLayout = "2006-01-02"
+
+// ValidGranularity reports whether g is a TrendFilter granularity.
+func ValidGranularity(g string) bool {
+	return g == GranularityDay || g == GranularityWeek || g == GranularityMonth
+}
+
+// TrendFilter selects the buckets and listings GetTrends reports on. Empty
+// fields match everything; Make and BodyType match case-insensitively.
+type TrendFilter struct {
+	Granularity string // one of the Granularity* constants
+	Category    string
+	Make        string
+	BodyType    string
+}
+
+// MaterializeDailyInventory rebuilds daily_inventory from the last day it
+// holds (or, when empty, the first day any listing was seen) through today,
+// all in one transaction. A listing is live on a day when it was first seen
+// before the day ended and not delisted before it began; its price is the
+// last price_history row recorded by the day's end, or its current price.
+// Returns the number of rows written.
+func MaterializeDailyInventory(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin daily inventory: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var from *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(
+			(SELECT MAX(day) FROM daily_inventory),
+			(SELECT MIN(first_seen AT TIME ZONE 'UTC')::date FROM listings)
+		)`).Scan(&from)
+	if err != nil {
+		return 0, fmt.Errorf("find daily inventory start: %w", err)
+	}
+	if from == nil {
+		return 0, nil
+	}
+	fromDay, today := from.Format(dayLayout), time.Now().UTC().Format(dayLayout)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM daily_inventory WHERE day >= $1::date`, fromDay); err != nil {
+		return 0, fmt.Errorf("clear daily inventory: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO daily_inventory (day, listing_id, category, make, body_type, price)
+		SELECT d.day, l.id, l.category, l.make, l.body_type,
+			COALESCE((
+				SELECT ph.price FROM price_history ph
+				WHERE ph.listing_id = l.id AND ph.recorded_at < d.day_end
+				ORDER BY ph.recorded_at DESC
+				LIMIT 1
+			), l.price)
+		FROM generate_series($1::timestamp, $2::timestamp, INTERVAL '1 day') AS g(ts)
+		CROSS JOIN LATERAL (
+			SELECT g.ts::date AS day,
+				g.ts AT TIME ZONE 'UTC' AS day_start,
+				(g.ts + INTERVAL '1 day') AT TIME ZONE 'UTC' AS day_end
+		) d
+		JOIN listings l
+		  ON l.first_seen < d.day_end
+		 AND COALESCE(l.delisted_at, CASE WHEN l.is_active THEN NULL ELSE l.last_seen END, 'infinity') >= d.day_start`,
+		fromDay, today,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("materialize daily inventory: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit daily inventory: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetTrends returns market figures per f.Granularity bucket, oldest first,
+// from daily_inventory (inventory and prices) and listings (new and delisted
+// counts).
+func GetTrends(ctx context.Context, pool *pgxpool.Pool, f TrendFilter) ([]models.TrendBucket, error) {
+	set := newTrendSet(f.Granularity)
+
+	rows, err := pool.Query(ctx, `
+		WITH inv AS (
+			SELECT date_trunc($1, day::timestamp)::date AS bucket, day, price
+			FROM daily_inventory
+			WHERE ($2 = '' OR category = $2)
+			  AND ($3 = '' OR LOWER(make) = LOWER($3))
+			  AND ($4 = '' OR LOWER(body_type) = LOWER($4))
+		)
+		SELECT inv.bucket,
+			COUNT(*)::int,
+			COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY inv.price) FILTER (WHERE inv.price > 0), 0)::float8,
+			COALESCE(AVG(inv.price) FILTER (WHERE inv.price > 0), 0)::float8
+		FROM inv
+		JOIN (SELECT bucket, MAX(day) AS day FROM inv GROUP BY bucket) last
+		  ON last.bucket = inv.bucket AND last.day = inv.day
+		GROUP BY inv.bucket
+	`, f.Granularity, f.Category, f.Make, f.BodyType)
+	if err != nil {
+		return nil, fmt.Errorf("query inventory trends: %w", err)
+	}
+	for rows.Next() {
+		var bucket time.Time
+		var inventory int
+		var medianPrice, avgPrice float64
+		if err := rows.Scan(&bucket, &inventory, &medianPrice, &avgPrice); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan inventory trend row: %w", err)
+		}
+		b := set.at(bucket)
+		b.Inventory, b.MedianPrice, b.AvgPrice = inventory, medianPrice, avgPrice
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	rows, err = pool.Query(ctx, `
+		WITH l AS (
+			SELECT first_seen, delisted_at
+			FROM listings
+			WHERE ($2 = '' OR category = $2)
+			  AND ($3 = '' OR LOWER(make) = LOWER($3))
+			  AND ($4 = '' OR LOWER(body_type) = LOWER($4))
+		)
+		SELECT date_trunc($1, first_seen AT TIME ZONE 'UTC')::date, COUNT(*)::int, 0
+		FROM l WHERE first_seen IS NOT NULL GROUP BY 1
+		UNION ALL
+		SELECT date_trunc($1, delisted_at AT TIME ZONE 'UTC')::date, 0, COUNT(*)::int
+		FROM l WHERE delisted_at IS NOT NULL GROUP BY 1
+	`, f.Granularity, f.Category, f.Make, f.BodyType)
+	if err != nil {
+		return nil, fmt.Errorf("query listing flow trends: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket time.Time
+		var added, delisted int
+		if err := rows.Scan(&bucket, &added, &delisted); err != nil {
+			return nil, fmt.Errorf("scan listing flow trend row: %w", err)
+		}
+		b := set.at(bucket)
+		b.New += added
+		b.Delisted += delisted
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return set.list(), nil
+}
+
+// trendSet accumulates TrendBuckets keyed by their first day.
+type trendSet struct {
+	granularity string
+	buckets     map[time.Time]*trendBucket
+}
+
+// trendBucket is a TrendBucket being accumulated. When inventory is counted
+// in Go, lastDay is the latest day seen so far and prices its listings'.
+type trendBucket struct {
+	models.TrendBucket
+	lastDay time.Time
+	prices  []float64
+}
+
+func newTrendSet(granularity string) *trendSet {
+	return &trendSet{granularity: granularity, buckets: make(map[time.Time]*trendBucket)}
+}
+
+// at returns the bucket containing t, creating it if needed.
+func (s *trendSet) at(t time.Time) *trendBucket {
+	start := bucketStart(t, s.granularity)
+	b, ok := s.buckets[start]
+	if !ok {
+		b = &trendBucket{TrendBucket: models.TrendBucket{Bucket: start.Format(dayLayout)}}
+		s.buckets[start] = b
+	}
+	return b
+}
+
+// addLive counts one listing live on day at price into day's bucket. Only
+// the bucket's latest day is kept.
+func (s *trendSet) addLive(day time.Time, price float64) {
+	b := s.at(day)
+	switch {
+	case day.Before(b.lastDay):
+		return
+	case day.After(b.lastDay):
+		b.lastDay, b.Inventory, b.prices = day, 0, b.prices[:0]
+	}
+	b.Inventory++
+	if price > 0 {
+		b.prices = append(b.prices, price)
+	}
+}
+
+// list returns the buckets oldest first, pricing those counted by addLive.
+func (s *trendSet) list() []models.TrendBucket {
+	out := make([]models.TrendBucket, 0, len(s.buckets))
+	for _, b := range s.buckets {
+		if len(b.prices) > 0 {
+			var sum float64
+			for _, p := range b.prices {
+				sum += p
+			}
+			b.AvgPrice = sum / float64(len(b.prices))
+			b.MedianPrice = median(b.prices)
+		}
+		out = append(out, b.TrendBucket)
+	}
+	slices.SortFunc(out, func(a, b models.TrendBucket) int { return cmp.Compare(a.Bucket, b.Bucket) })
+	return out
+}
+
+// bucketStart returns the first day, at midnight UTC, of the bucket holding t.
+func bucketStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case GranularityWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case GranularityMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
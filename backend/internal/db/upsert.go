@@ -0,0 +1,363 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"ecaycar/backend/models"
+)
+
+// upsertBatchSize caps the statements queued per pgx batch. Under the simple
+// protocol InitDB configures for PgBouncer, pgx sends a batch as a single
+// multi-statement query string, so batches are kept to a modest size.
+const upsertBatchSize = 500
+
+// upsertListingSQL inserts a listing or updates the row with the same
+// external_id, returning its id. Arguments come from upsertListingArgs.
+const upsertListingSQL = `
+	INSERT INTO listings
+		(external_id, url, title, make, model, year, mileage, price, currency,
+		 images, location, condition, transmission, fuel_type, color,
+		 body_type, drive, cylinders, steering, interior_color, doors, on_island,
+		 category, attributes, description, seller_name, seller_url, posted_at, ad_updated_at,
+		 card_title, is_active, last_seen)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,
+	        $23,$24::jsonb,NULLIF($25,''),NULLIF($26,''),NULLIF($27,''),$28,$29,NULLIF($30,''),TRUE,NOW())
+	ON CONFLICT (external_id) DO UPDATE SET
+		url            = EXCLUDED.url,
+		title          = EXCLUDED.title,
+		make           = EXCLUDED.make,
+		model          = EXCLUDED.model,
+		year           = EXCLUDED.year,
+		mileage        = EXCLUDED.mileage,
+		price          = EXCLUDED.price,
+		currency       = EXCLUDED.currency,
+		images         = EXCLUDED.images,
+		location       = EXCLUDED.location,
+		condition      = EXCLUDED.condition,
+		transmission   = EXCLUDED.transmission,
+		fuel_type      = EXCLUDED.fuel_type,
+		color          = EXCLUDED.color,
+		body_type      = EXCLUDED.body_type,
+		drive          = EXCLUDED.drive,
+		cylinders      = EXCLUDED.cylinders,
+		steering       = EXCLUDED.steering,
+		interior_color = EXCLUDED.interior_color,
+		doors          = EXCLUDED.doors,
+		on_island      = EXCLUDED.on_island,
+		category       = EXCLUDED.category,
+		attributes     = EXCLUDED.attributes,
+		description    = COALESCE(EXCLUDED.description, listings.description),
+		seller_name    = COALESCE(EXCLUDED.seller_name, listings.seller_name),
+		seller_url     = COALESCE(EXCLUDED.seller_url, listings.seller_url),
+		posted_at      = COALESCE(EXCLUDED.posted_at, listings.posted_at),
+		ad_updated_at  = COALESCE(EXCLUDED.ad_updated_at, listings.ad_updated_at),
+		card_title     = COALESCE(EXCLUDED.card_title, listings.card_title),
+		is_active      = TRUE,
+		last_seen      = NOW(),
+		delisted_at    = NULL,
+		updated_at     = NOW()
+	RETURNING id`
+
+// UpsertResult describes what happened when a listing was upserted.
+type UpsertResult struct {
+	Inserted     bool
+	PriceChanged bool
+	// Changes lists every scraped field that differed from the stored row.
+	Changes []models.FieldChange
+}
+
+// UpsertListing upserts a single listing in its own transaction. See
+// UpsertListings.
+func UpsertListing(ctx context.Context, pool *pgxpool.Pool, l models.Listing) (UpsertResult, error) {
+	results, err := UpsertListings(ctx, pool, []models.Listing{l})
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	return results[0], nil
+}
+
+// UpsertListings inserts new listings and updates existing ones matched on
+// external_id, all in one transaction: either every listing is written with
+// its history rows, or nothing is. It returns one UpsertResult per listing,
+// in input order.
+//
+// A new listing gets its asking price as the first price_history row, stamped
+// with first_seen; an existing one gets a row whenever the price has changed.
+// Every field that differs from the stored row is recorded in listing_changes.
+// Description, seller and ad dates come only from the detail page, so a run
+// whose detail fetch failed keeps the stored values; a CardOnly listing keeps
+// its stored gallery too.
+//
+// Besides the initial SELECT, statements are sent in pgx batches of up to
+// upsertBatchSize, which works under the simple query protocol.
+func UpsertListings(ctx context.Context, pool *pgxpool.Pool, listings []models.Listing) ([]UpsertResult, error) {
+	results := make([]UpsertResult, len(listings))
+	if len(listings) == 0 {
+		return results, nil
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin upsert: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// ── 1. Load the existing rows to diff against ──
+	ids := make([]string, len(listings))
+	for i, l := range listings {
+		ids[i] = l.ExternalID
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT `+listingColumns+`
+		FROM listings
+		WHERE external_id = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query existing listings: %w", err)
+	}
+	stored, err := collectListings(rows)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]models.Listing, len(stored))
+	for _, l := range stored {
+		existing[l.ExternalID] = l
+	}
+
+	// Listings whose detail fetch failed keep the stored detail fields, both
+	// in the row written and in the diff.
+	merged := make([]models.Listing, len(listings))
+	for i, l := range listings {
+		if prev, ok := existing[l.ExternalID]; ok {
+			l = keepStoredDetails(l, prev)
+		}
+		merged[i] = l
+	}
+
+	// ── 2. Upsert the listings, collecting their ids ──
+	listingIDs := make([]string, len(listings))
+	for start := 0; start < len(listings); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(listings))
+		b := &pgx.Batch{}
+		for _, l := range merged[start:end] {
+			args, err := upsertListingArgs(l)
+			if err != nil {
+				return nil, err
+			}
+			b.Queue(upsertListingSQL, args...)
+		}
+		if err := sendUpsertBatch(ctx, tx, b, merged[start:end], listingIDs[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	// ── 3. Record price history and field-level changes ──
+	var history []queuedStmt
+	for i, l := range listings {
+		res := &results[i]
+		prev, ok := existing[l.ExternalID]
+		switch {
+		case !ok:
+			res.Inserted = true
+			if l.Price > 0 {
+				history = append(history, queuedStmt{l.ExternalID, `
+					INSERT INTO price_history (listing_id, price, recorded_at)
+					SELECT id, price, first_seen FROM listings WHERE id = $1`,
+					[]any{listingIDs[i]},
+				})
+			}
+		default:
+			res.Changes = DiffListings(prev, keepStoredDetails(l, prev))
+			if prev.Price != l.Price && l.Price > 0 {
+				res.PriceChanged = true
+				history = append(history, queuedStmt{l.ExternalID,
+					`INSERT INTO price_history (listing_id, price) VALUES ($1, $2)`,
+					[]any{listingIDs[i], l.Price},
+				})
+			}
+		}
+		for _, c := range res.Changes {
+			history = append(history, queuedStmt{l.ExternalID, `
+				INSERT INTO listing_changes (listing_id, field, old_value, new_value)
+				VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))`,
+				[]any{listingIDs[i], c.Field, c.Old, c.New},
+			})
+		}
+		// A listing scraped twice in one run diffs against its first copy.
+		existing[l.ExternalID] = keepStoredDetails(l, prev)
+	}
+	for start := 0; start < len(history); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(history))
+		if err := execBatch(ctx, tx, history[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit upsert: %w", err)
+	}
+	return results, nil
+}
+
+// queuedStmt is one history statement waiting to be batched; externalID
+// identifies the listing in error messages.
+type queuedStmt struct {
+	externalID string
+	sql        string
+	args       []any
+}
+
+// sendUpsertBatch sends a batch of upsertListingSQL statements, one per
+// listing, and stores each returned id in ids.
+func sendUpsertBatch(ctx context.Context, tx pgx.Tx, b *pgx.Batch, listings []models.Listing, ids []string) error {
+	br := tx.SendBatch(ctx, b)
+	for i, l := range listings {
+		if err := br.QueryRow().Scan(&ids[i]); err != nil {
+			_ = br.Close()
+			return fmt.Errorf("upsert listing %s: %w", l.ExternalID, err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("upsert listings: %w", err)
+	}
+	return nil
+}
+
+// execBatch sends stmts as one batch and checks every result.
+func execBatch(ctx context.Context, tx pgx.Tx, stmts []queuedStmt) error {
+	b := &pgx.Batch{}
+	for _, s := range stmts {
+		b.Queue(s.sql, s.args...)
+	}
+	br := tx.SendBatch(ctx, b)
+	for _, s := range stmts {
+		if _, err := br.Exec(); err != nil {
+			_ = br.Close()
+			return fmt.Errorf("record history for %s: %w", s.externalID, err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+	return nil
+}
+
+// upsertListingArgs returns the arguments of upsertListingSQL for l.
+func upsertListingArgs(l models.Listing) ([]any, error) {
+	attrs, err := attributesJSON(l)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		l.ExternalID, l.URL, l.Title, l.Make, l.Model,
+		l.Year, l.Mileage, l.Price, l.Currency,
+		l.Images, l.Location, l.Condition, l.Transmission, l.FuelType, l.Color,
+		l.BodyType, l.Drive, l.Cylinders, l.Steering, l.InteriorColor, l.Doors, l.OnIsland,
+		categoryOrDefault(l.Category), attrs,
+		l.Description, l.SellerName, l.SellerURL, l.PostedAt, l.AdUpdatedAt,
+		l.CardTitle,
+	}, nil
+}
+
+// keepAdDate reconciles a stored ad date with a freshly scraped one. A
+// relative date ("3 weeks ago") is resolved against each crawl's clock, so
+// the fresh value is only the latest time the ad could date from, give or
+// take slack. The stored value is kept while it still fits that window, so
+// the date does not creep forward run after run; a posting date, which never
+// changes, keeps whichever of the two is earlier.
+func keepAdDate(stored, fresh *time.Time, slack time.Duration, earliest bool) *time.Time {
+	switch {
+	case fresh == nil:
+		return stored
+	case stored == nil:
+		return fresh
+	case earliest && stored.Before(*fresh):
+		return stored
+	case !stored.After(*fresh) && fresh.Sub(*stored) < slack:
+		return stored
+	}
+	return fresh
+}
+
+// keepStoredDetails fills the detail-only fields that upsertListingSQL never
+// blanks out (see its COALESCE clauses) from the stored row, so a failed
+// detail fetch is not recorded as the seller deleting them. A CardOnly
+// listing also keeps every other field the detail page supplies, unless the
+// card itself carried a value, and the stored gallery instead of its card
+// thumbnail. The stores write the result and diff against it, so neither
+// the row nor listing_changes sees the missing detail page.
+func keepStoredDetails(l, prev models.Listing) models.Listing {
+	if l.Description == "" {
+		l.Description = prev.Description
+	}
+	if l.SellerName == "" {
+		l.SellerName = prev.SellerName
+	}
+	if l.SellerURL == "" {
+		l.SellerURL = prev.SellerURL
+	}
+	l.PostedAt = keepAdDate(prev.PostedAt, l.PostedAt, l.PostedSlack, true)
+	l.AdUpdatedAt = keepAdDate(prev.AdUpdatedAt, l.AdUpdatedAt, l.UpdatedSlack, false)
+	if !l.CardOnly {
+		return l
+	}
+
+	if l.Year == nil {
+		l.Year = prev.Year
+	}
+	if l.Mileage == nil {
+		l.Mileage = prev.Mileage
+	}
+	if l.Condition == "" {
+		l.Condition = prev.Condition
+	}
+	if l.Transmission == "" {
+		l.Transmission = prev.Transmission
+	}
+	if l.FuelType == "" {
+		l.FuelType = prev.FuelType
+	}
+	if l.Color == "" {
+		l.Color = prev.Color
+	}
+	if l.BodyType == "" {
+		l.BodyType = prev.BodyType
+	}
+	if l.Drive == "" {
+		l.Drive = prev.Drive
+	}
+	if l.Cylinders == "" {
+		l.Cylinders = prev.Cylinders
+	}
+	if l.Steering == "" {
+		l.Steering = prev.Steering
+	}
+	if l.InteriorColor == "" {
+		l.InteriorColor = prev.InteriorColor
+	}
+	if l.Doors == "" {
+		l.Doors = prev.Doors
+	}
+	if l.OnIsland == nil {
+		l.OnIsland = prev.OnIsland
+	}
+	if len(prev.Attributes) > 0 {
+		attrs := make(map[string]string, len(prev.Attributes)+len(l.Attributes))
+		for k, v := range prev.Attributes {
+			attrs[k] = v
+		}
+		for k, v := range l.Attributes {
+			attrs[k] = v
+		}
+		l.Attributes = attrs
+	}
+	if len(prev.Images) > 0 {
+		l.Images = prev.Images
+	}
+	return l
+}
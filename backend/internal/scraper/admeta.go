@@ -0,0 +1,196 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// Keys under which adMetaJS results are merged into the detail field map, so
+// they are archived with the snapshot and re-parsed like any other field.
+const (
+	fieldDescription = "description"
+	fieldSellerName  = "seller name"
+	fieldSellerURL   = "seller url"
+	fieldSellerBadge = "seller badges"
+	fieldPosted      = "posted"
+	fieldUpdated     = "updated"
+)
+
+// adMetaJS is injected into each listing detail page alongside detailJS. It
+// returns the free-text parts of the ad that are not "Ad Details" label/value
+// pairs: the description, the seller's name, profile link and badges
+// ("|"-separated), and the raw posted/updated date strings, keyed by the
+// field* constants.
+const adMetaJS = `() => {
+const out = {};
+const text = (el) => (el ? (el.innerText || el.textContent || '') : '').trim();
+
+// ── Description: block after a "Description" heading, a description-classed
+// element, or the page's meta description, in that order ──
+for (const h of document.querySelectorAll('h1, h2, h3, h4, h5, strong, b, dt')) {
+  if (!/^(ad\s+)?description:?$/i.test(h.textContent.trim())) continue;
+  const el = h.nextElementSibling || (h.parentElement && h.parentElement.nextElementSibling);
+  if (text(el)) { out['description'] = text(el); break; }
+}
+if (!out['description']) {
+  const el = document.querySelector('[itemprop="description"], [class*="description" i]');
+  if (text(el)) out['description'] = text(el);
+}
+if (!out['description']) {
+  const m = document.querySelector('meta[property="og:description"], meta[name="description"]');
+  if (m && m.content) out['description'] = m.content.trim();
+}
+
+// ── Seller: the first link to a user, store or dealer profile ──
+const seller = document.querySelector([
+  'a[href*="/profile/"]', 'a[href*="/user/"]', 'a[href*="/users/"]',
+  'a[href*="/store/"]', 'a[href*="/dealer/"]', 'a[href*="/seller/"]'
+].join(', '));
+if (seller) {
+  out['seller url'] = seller.href;
+  const name = text(seller).split('\n')[0].trim();
+  if (name && name.length < 100) out['seller name'] = name;
+  // Badges such as "Verified Dealer" or "Store" shown next to the seller.
+  const box = seller.closest('aside, section, [class*="seller" i], [class*="user" i]') || seller.parentElement;
+  const badges = new Set();
+  if (box) {
+    for (const b of box.querySelectorAll('[class*="badge" i], [class*="verified" i], [class*="dealer" i], [class*="store" i], [class*="business" i]')) {
+      const t = text(b);
+      if (t && t.length < 40) badges.add(t);
+    }
+  }
+  if (badges.size) out['seller badges'] = [...badges].join('|');
+}
+
+// ── Dates: machine-readable <time> elements first, then visible text ──
+for (const t of document.querySelectorAll('time[datetime]')) {
+  const around = (t.parentElement ? t.parentElement.textContent : '').toLowerCase();
+  const key = around.includes('updated') ? 'updated' : 'posted';
+  if (!out[key]) out[key] = t.getAttribute('datetime');
+}
+const lines = document.body.innerText.split('\n').map(l => l.trim()).filter(Boolean);
+for (let i = 0; i < lines.length; i++) {
+  const m = lines[i].match(/^(posted|listed|published|date posted|updated|last updated|renewed)(?:\s+on)?\s*:?\s*(.*)$/i);
+  if (!m) continue;
+  const key = /updated|renewed/i.test(m[1]) ? 'updated' : 'posted';
+  const val = m[2] || lines[i + 1] || '';
+  if (!out[key] && val && val.length < 60) out[key] = val;
+}
+return out;
+}`
+
+// caymanTime is the site's local time zone (UTC-5, no daylight saving), used
+// for dates shown without one.
+var caymanTime = time.FixedZone("EST", -5*60*60)
+
+// relativeDateRe matches "3 days ago", "an hour ago", "1 week ago" etc.
+var relativeDateRe = regexp.MustCompile(`(?i)^(a|an|\d+)\s+(minute|hour|day|week|month|year)s?\s+ago$`)
+
+// adDateLayouts are the absolute date formats tried by parseAdDate.
+var adDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"January 2 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02/01/2006", // day first
+}
+
+// ApplyAdDetails fills the description, seller and posted/updated dates of l
+// from the adMetaJS entries of a detail field map. seen is when the page was
+// captured; relative dates ("3 days ago") are resolved against it. Existing
+// non-zero values are not overwritten.
+func ApplyAdDetails(fields map[string]string, seen time.Time, l *models.Listing) {
+	get := func(key string) string { return strings.TrimSpace(fields[key]) }
+
+	if l.Description == "" {
+		l.Description = get(fieldDescription)
+	}
+	if l.SellerName == "" {
+		l.SellerName = get(fieldSellerName)
+	}
+	if l.SellerURL == "" {
+		l.SellerURL = get(fieldSellerURL)
+	}
+	if l.SellerMarkers == nil {
+		for _, b := range strings.Split(get(fieldSellerBadge), "|") {
+			if b = strings.TrimSpace(b); b != "" {
+				l.SellerMarkers = append(l.SellerMarkers, b)
+			}
+		}
+	}
+	if l.PostedAt == nil {
+		l.PostedAt, l.PostedSlack = parseAdDate(get(fieldPosted), seen)
+	}
+	if l.AdUpdatedAt == nil {
+		l.AdUpdatedAt, l.UpdatedSlack = parseAdDate(get(fieldUpdated), seen)
+	}
+}
+
+// parseAdDate parses an absolute or relative ad date as shown on ecaytrade.
+// It returns nil when raw is empty, unrecognised or in the future. For a
+// relative date it also returns the unit it was given in: "3 weeks ago"
+// only says the date lies within a week before the returned time, and the
+// stores use that to avoid moving the date forward on every crawl.
+func parseAdDate(raw string, seen time.Time) (*time.Time, time.Duration) {
+	raw = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw), "."))
+	if raw == "" {
+		return nil, 0
+	}
+
+	var t time.Time
+	var slack time.Duration
+	switch lower := strings.ToLower(raw); {
+	case lower == "just now":
+		t, slack = seen, time.Minute
+	case lower == "today":
+		t, slack = seen, 24*time.Hour
+	case lower == "yesterday":
+		t, slack = seen.AddDate(0, 0, -1), 24*time.Hour
+	case relativeDateRe.MatchString(raw):
+		m := relativeDateRe.FindStringSubmatch(raw)
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			n = 1 // "a day ago", "an hour ago"
+		}
+		switch strings.ToLower(m[2]) {
+		case "minute":
+			t, slack = seen.Add(-time.Duration(n)*time.Minute), time.Minute
+		case "hour":
+			t, slack = seen.Add(-time.Duration(n)*time.Hour), time.Hour
+		case "day":
+			t, slack = seen.AddDate(0, 0, -n), 24*time.Hour
+		case "week":
+			t, slack = seen.AddDate(0, 0, -7*n), 7*24*time.Hour
+		case "month":
+			t, slack = seen.AddDate(0, -n, 0), 31*24*time.Hour
+		case "year":
+			t, slack = seen.AddDate(-n, 0, 0), 366*24*time.Hour
+		}
+	default:
+		ok := false
+		for _, layout := range adDateLayouts {
+			if v, err := time.ParseInLocation(layout, raw, caymanTime); err == nil {
+				t, ok = v, true
+				break
+			}
+		}
+		if !ok {
+			return nil, 0
+		}
+	}
+
+	if t.After(seen.Add(24 * time.Hour)) {
+		return nil, 0
+	}
+	return &t, slack
+}
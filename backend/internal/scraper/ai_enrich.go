@@ -45,8 +45,12 @@ type aiCarResult struct {
 }
 
 // needsEnrichment returns true when the listing's make doesn't match any known
-// make, suggesting the scraper couldn't parse it cleanly.
+// make, suggesting the scraper couldn't parse it cleanly. Only cars are
+// enriched: the prompt and knownMakes are car-specific.
 func needsEnrichment(l models.Listing) bool {
+	if categoryOf(l).Slug != DefaultCategory {
+		return false
+	}
 	if l.Make == "" {
 		return true
 	}
@@ -65,7 +69,8 @@ func needsEnrichment(l models.Listing) bool {
 //
 // If the token is empty or the API call fails for a listing, the original
 // values are preserved and a warning is logged — enrichment is best-effort.
-func EnrichListings(ctx context.Context, listings []models.Listing, githubToken string) []models.Listing {
+// Each API call is counted in rec, which may be nil.
+func EnrichListings(ctx context.Context, listings []models.Listing, githubToken string, rec *Recorder) []models.Listing {
 	if githubToken == "" {
 		log.Println("[ai_enrich] GITHUB_TOKEN not set — skipping AI enrichment")
 		return listings
@@ -92,6 +97,7 @@ func EnrichListings(ctx context.Context, listings []models.Listing, githubToken
 	for count, i := range toEnrich {
 		l := &enriched[i]
 		result, err := enrichOne(ctx, client, githubToken, *l)
+		rec.EnrichCall(err != nil)
 		if err != nil {
 			log.Printf("[ai_enrich] WARNING: failed to enrich listing %s (%q): %v", l.ExternalID, l.Title, err)
 			continue
@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// ErrBlocked means the site served a bot challenge (e.g. Cloudflare) instead
+// of content. It is distinct from a genuinely empty page: a blocked run is
+// partial and must be alerted on, not treated as the end of the listings.
+var ErrBlocked = errors.New("blocked by bot challenge")
+
+// blockProbeJS reports the navigation's HTTP status, the document title and
+// which known challenge markers are present in the DOM.
+const blockProbeJS = `() => {
+const nav = performance.getEntriesByType('navigation')[0];
+const markers = [
+  '#challenge-form', '#challenge-running', '#challenge-stage',
+  '#cf-challenge-running', '#cf-wrapper', '.cf-browser-verification',
+  '#turnstile-wrapper', 'iframe[src*="challenges.cloudflare.com"]',
+  'script[src*="/cdn-cgi/challenge-platform/"]'
+];
+return {
+  status:  nav && nav.responseStatus ? nav.responseStatus : 0,
+  title:   document.title || '',
+  markers: markers.filter(m => document.querySelector(m))
+};
+}`
+
+// challengeTitles are document titles Cloudflare uses on its interstitials.
+// Generic phrases such as "please wait" are deliberately absent: an ordinary
+// page can carry them, and the DOM markers catch those challenges anyway.
+var challengeTitles = []string{
+	"just a moment...",
+	"attention required! | cloudflare",
+	"checking your browser before accessing",
+}
+
+// detectBlock probes a loaded page and returns an error wrapping ErrBlocked
+// when it looks like a bot challenge, or nil otherwise (including when the
+// probe itself fails — absence of evidence is not a block).
+func detectBlock(page *rod.Page) error {
+	res, err := page.Eval(blockProbeJS)
+	if err != nil {
+		return nil
+	}
+	obj := res.Value.Map()
+	var markers []string
+	for _, m := range obj["markers"].Arr() {
+		markers = append(markers, m.Str())
+	}
+	if reason := classifyBlock(obj["status"].Int(), obj["title"].Str(), markers); reason != "" {
+		return fmt.Errorf("%w: %s", ErrBlocked, reason)
+	}
+	return nil
+}
+
+// classifyBlock decides whether a page is a challenge from its HTTP status,
+// title and challenge DOM markers. It returns a short reason, or "" when the
+// page does not look blocked. A 403/503 on its own is not enough — the site
+// may simply be erroring — but 429 always means we are being rate limited.
+func classifyBlock(status int, title string, markers []string) string {
+	lower := strings.ToLower(title)
+	for _, t := range challengeTitles {
+		if strings.Contains(lower, t) {
+			return fmt.Sprintf("challenge title %q (status %d)", title, status)
+		}
+	}
+	if len(markers) > 0 {
+		return fmt.Sprintf("challenge markers %s (status %d)", strings.Join(markers, ", "), status)
+	}
+	if status == 429 {
+		return "HTTP 429 Too Many Requests"
+	}
+	return ""
+}
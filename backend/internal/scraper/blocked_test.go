@@ -0,0 +1,42 @@
+package scraper
+
+import "testing"
+
+func TestClassifyBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		title   string
+		markers []string
+		blocked bool
+	}{
+		{"ordinary page", 200, "Cars for Sale | ecaytrade", nil, false},
+		{"cloudflare interstitial", 503, "Just a moment...", nil, true},
+		{"cloudflare block page", 403, "Attention Required! | Cloudflare", nil, true},
+		{"browser check", 200, "Checking your browser before accessing ecaytrade.com", nil, true},
+		{"challenge markers only", 200, "", []string{"#challenge-form"}, true},
+		{"rate limited", 429, "", nil, true},
+		{"bare 403", 403, "Forbidden", nil, false},
+		{"bare 503", 503, "Service Unavailable", nil, false},
+		{"generic please wait", 200, "Please wait while we load your results", nil, false},
+		{"generic access denied", 200, "Access denied - members only listing", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := classifyBlock(tt.status, tt.title, tt.markers)
+			if got := reason != ""; got != tt.blocked {
+				t.Errorf("classifyBlock(%d, %q, %v) = %q, want blocked=%v", tt.status, tt.title, tt.markers, reason, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestRecorderBlockedCountsURLOnce(t *testing.T) {
+	rec := NewRecorder()
+	rec.Blocked("https://example.com/a")
+	rec.Blocked("https://example.com/a")
+	rec.Blocked("https://example.com/b")
+	if got := rec.Run().BlockedPages; got != 2 {
+		t.Errorf("BlockedPages = %d, want 2", got)
+	}
+}
@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ecaycar/backend/models"
+)
+
+// DefaultCategory is the category of listings scraped before categories
+// existed, and the one crawled when SCRAPE_CATEGORIES is unset.
+const DefaultCategory = "autos"
+
+// Category describes one ecaytrade listings section the scraper can crawl.
+type Category struct {
+	// Slug is stored in listings.category and accepted by the API filter.
+	Slug string
+	// Path is the section path on ecaytrade.com, without a leading slash.
+	Path string
+	// MinPrice is applied both in the URL filter and as a code-level safety
+	// net, to keep parts and accessories out.
+	MinPrice float64
+	// RequireYear skips cards with no model year. Boats often omit it.
+	RequireYear bool
+	// Makes extends knownMakes for splitting make vs model in titles.
+	Makes []string
+	// DetailLabels are extra "Ad Details" labels extracted for this category.
+	// They are stored in Listing.Attributes keyed by the label with spaces
+	// replaced by underscores (e.g. "engine hours" → "engine_hours").
+	DetailLabels []string
+}
+
+// categories is the registry of crawlable sections, in crawl order.
+var categories = []Category{
+	{
+		Slug:        "autos",
+		Path:        "autos-boats/autos",
+		MinPrice:    4000,
+		RequireYear: true,
+	},
+	{
+		Slug:     "boats",
+		Path:     "autos-boats/boats",
+		MinPrice: 2000,
+		Makes: []string{
+			"Boston Whaler", "Bayliner", "Chaparral", "Contender", "Edgewater",
+			"Grady-White", "Intrepid", "Mako", "Regal", "Robalo", "Sea Fox",
+			"Sea Ray", "Sea-Doo", "Scout", "Yellowfin", "Yamaha",
+		},
+		DetailLabels: []string{
+			"length", "engine hours", "hull material", "engine make",
+			"number of engines", "horsepower", "boat type",
+		},
+	},
+	{
+		Slug:        "motorcycles",
+		Path:        "autos-boats/motorcycles",
+		MinPrice:    1000,
+		RequireYear: true,
+		Makes: []string{
+			"Harley-Davidson", "Ducati", "Kawasaki", "KTM", "Triumph", "Vespa",
+		},
+		DetailLabels: []string{"engine size"},
+	},
+	{
+		Slug:        "commercial",
+		Path:        "autos-boats/commercial-vehicles",
+		MinPrice:    4000,
+		RequireYear: true,
+		Makes:       []string{"Isuzu", "Hino", "Freightliner", "International", "Mack"},
+		DetailLabels: []string{
+			"payload", "gross vehicle weight", "axles", "engine hours",
+		},
+	},
+}
+
+// LookupCategory returns the registered category with the given slug. An
+// empty slug means DefaultCategory.
+func LookupCategory(slug string) (Category, bool) {
+	if slug == "" {
+		slug = DefaultCategory
+	}
+	for _, c := range categories {
+		if c.Slug == slug {
+			return c, true
+		}
+	}
+	return Category{}, false
+}
+
+// categoryOf returns the category a listing belongs to, falling back to
+// DefaultCategory for listings that predate categories.
+func categoryOf(l models.Listing) Category {
+	if c, ok := LookupCategory(l.Category); ok {
+		return c
+	}
+	c, _ := LookupCategory(DefaultCategory)
+	return c
+}
+
+// CategorySlugs returns the slugs of every registered category.
+func CategorySlugs() []string {
+	slugs := make([]string, len(categories))
+	for i, c := range categories {
+		slugs[i] = c.Slug
+	}
+	return slugs
+}
+
+// categoriesFromEnv returns the categories named in SCRAPE_CATEGORIES
+// (comma-separated slugs, or "all"), defaulting to DefaultCategory.
+func categoriesFromEnv() ([]Category, error) {
+	raw := strings.TrimSpace(os.Getenv("SCRAPE_CATEGORIES"))
+	if raw == "" {
+		raw = DefaultCategory
+	}
+	if strings.EqualFold(raw, "all") {
+		return categories, nil
+	}
+
+	var cats []Category
+	seen := make(map[string]bool)
+	for _, slug := range strings.Split(raw, ",") {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug == "" || seen[slug] {
+			continue
+		}
+		c, ok := LookupCategory(slug)
+		if !ok {
+			return nil, fmt.Errorf("SCRAPE_CATEGORIES: unknown category %q (known: %s)",
+				slug, strings.Join(CategorySlugs(), ", "))
+		}
+		seen[slug] = true
+		cats = append(cats, c)
+	}
+	return cats, nil
+}
+
+// listingsURL returns the live URL of the category's first listings page.
+func (c Category) listingsURL() string {
+	return fmt.Sprintf("%s/%s?minprice=%.0f", siteURL, c.Path, c.MinPrice)
+}
+
+// detailLabels returns the category's extra labels in the lower-case form
+// detailJS matches against.
+func (c Category) detailLabels() []string {
+	labels := make([]string, len(c.DetailLabels))
+	for i, lb := range c.DetailLabels {
+		labels[i] = strings.ToLower(lb)
+	}
+	return labels
+}
+
+// ParseCategoryCard parses a listing card from the given category: ParseCard,
+// then the category's own makes for the make/model split.
+func ParseCategoryCard(c Category, cardText, rawURL, imgURL string) models.Listing {
+	l := ParseCard(cardText, rawURL, imgURL)
+	l.Category = c.Slug
+	if mk, model, ok := matchMake(l.Title, c.Makes); ok {
+		l.Make, l.Model = mk, model
+	}
+	return l
+}
+
+// ApplyCategoryFields copies the category's detail labels from fields into
+// l.Attributes. Values already present are kept.
+func ApplyCategoryFields(c Category, fields map[string]string, l *models.Listing) {
+	for _, label := range c.detailLabels() {
+		v := strings.TrimSpace(fields[label])
+		if v == "" || contaminatedRe.MatchString(v) {
+			continue
+		}
+		key := strings.ReplaceAll(label, " ", "_")
+		if l.Attributes == nil {
+			l.Attributes = make(map[string]string)
+		}
+		if _, ok := l.Attributes[key]; !ok {
+			l.Attributes[key] = v
+		}
+	}
+}
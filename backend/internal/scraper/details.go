@@ -0,0 +1,169 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+
+	"ecaycar/backend/models"
+)
+
+// cardJob carries one raw card through parsing, filtering and the detail fetch.
+type cardJob struct {
+	idx       int // card index on its listings page
+	page      int // listings page number, for logging
+	listing   models.Listing
+	snap      models.ListingSnapshot
+	accepted  bool  // passed Qualify; needs its detail page
+	unchanged bool  // passed Qualify but matches the known listing
+	detailErr error // detail fetch failed; the card-level listing is kept
+	dropped   bool  // a panic occurred while processing the card
+}
+
+// rateLimiter spaces out detail-page navigations across all workers so the
+// total request rate stays polite however many pages run concurrently.
+// A nil *rateLimiter never waits.
+type rateLimiter struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	if interval <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: interval}
+}
+
+// wait blocks until the caller's turn, then reserves the next slot one
+// interval (plus up to 50% jitter) later.
+func (r *rateLimiter) wait() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	now := time.Now()
+	at := r.next
+	if at.Before(now) {
+		at = now
+	}
+	r.next = at.Add(r.interval + time.Duration(rand.Int63n(int64(r.interval)/2+1)))
+	r.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+// fetchDetails fetches the detail page of every accepted job using a bounded
+// pool of browser pages. Each job writes only to itself, so results stay in
+// card order regardless of completion order.
+func (s *session) fetchDetails(jobs []*cardJob) {
+	var pending []*cardJob
+	for _, j := range jobs {
+		if j.accepted && !j.dropped {
+			pending = append(pending, j)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	queue := make(chan *cardJob)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.detailWorkers, len(pending)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.detailWorker(queue)
+		}()
+	}
+	for _, j := range pending {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// detailWorker owns one stealth page and reuses it for every job it takes.
+func (s *session) detailWorker(queue <-chan *cardJob) {
+	var page *rod.Page
+	defer func() {
+		if page != nil {
+			_ = page.Close()
+		}
+	}()
+
+	for j := range queue {
+		if page == nil {
+			p, err := stealth.Page(s.browser)
+			if err != nil {
+				j.detailErr = fmt.Errorf("detail stealth.Page: %w", err)
+				continue
+			}
+			page = p
+		}
+
+		s.limiter.wait()
+		if !s.runDetailJob(page, j) {
+			// The page may be stuck mid-navigation after a panic; start afresh.
+			_ = page.Close()
+			page = nil
+		}
+	}
+}
+
+// runDetailJob fetches one detail page, isolating panics to the card that
+// caused them. It returns false if the job panicked.
+func (s *session) runDetailJob(page *rod.Page, j *cardJob) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[page %d / card %d] panic: %v", j.page, j.idx, r)
+			j.dropped = true
+			ok = false
+		}
+	}()
+	j.detailErr = s.fetchAndApplyDetailFields(page, &j.listing, &j.snap, j.page, j.idx)
+	return true
+}
+
+// queuedDetail is a listing whose detail fetch failed during pagination.
+type queuedDetail struct {
+	pos int // index of the listing in its result slice
+	job *cardJob
+}
+
+// retryFailedDetails gives detail pages that failed during pagination one
+// more pass at the end of the run, by which time a transient block has often
+// cleared. Recovered listings replace their card-level version in res. Every
+// queued card is archived here, recovered or not; scrapePage skipped them.
+func (s *session) retryFailedDetails(res *Result, queue []queuedDetail) {
+	log.Printf("Second pass: retrying %d failed detail page(s)...", len(queue))
+
+	jobs := make([]*cardJob, len(queue))
+	for i, q := range queue {
+		q.job.detailErr = nil
+		jobs[i] = q.job
+	}
+	s.fetchDetails(jobs)
+
+	recovered := 0
+	for _, q := range queue {
+		j := q.job
+		s.archiveSnapshot(&j.snap)
+		if j.dropped || j.detailErr != nil {
+			if j.detailErr != nil {
+				log.Printf("[page %d / card %d] %v — giving up", j.page, j.idx, j.detailErr)
+			}
+			s.rec.DetailFailed()
+			continue
+		}
+		res.Listings[q.pos] = j.listing
+		recovered++
+	}
+	log.Printf("Second pass: recovered %d/%d detail page(s)", recovered, len(queue))
+}
@@ -39,6 +39,10 @@ var (
 
 	// digitsRe extracts the first run of digits (and commas) from a string.
 	digitsRe = regexp.MustCompile(`[\d,]+`)
+
+	// imageSizeRe matches a "-300x200" style size suffix before an image
+	// file extension, as added to resized copies of the same photo.
+	imageSizeRe = regexp.MustCompile(`[-_]\d+x\d+(\.[a-zA-Z]+)$`)
 )
 
 // knownMakes is a rough list of common makes to help split make vs model.
@@ -97,6 +101,7 @@ func ParseCard(cardText, rawURL, imgURL string) models.Listing {
 
 	// Build title: take the first non-empty line that isn't just a price
 	l.Title = extractTitle(cardText)
+	l.CardTitle = l.Title
 
 	// Split make + model from title
 	l.Make, l.Model = splitMakeModel(l.Title)
@@ -139,13 +144,8 @@ func splitMakeModel(title string) (make_, model string) {
 	stripped := yearRe.ReplaceAllString(title, "")
 	stripped = strings.TrimSpace(stripped)
 
-	titleUpper := strings.ToUpper(stripped)
-	for _, mk := range knownMakes {
-		if strings.HasPrefix(titleUpper, strings.ToUpper(mk)) {
-			make_ = mk
-			model = strings.TrimSpace(stripped[len(mk):])
-			return
-		}
+	if mk, rest, ok := matchMake(title, knownMakes); ok {
+		return mk, rest
 	}
 
 	// Fallback: first word is make, rest is model
@@ -159,6 +159,19 @@ func splitMakeModel(title string) (make_, model string) {
 	return parts[0], strings.Join(parts[1:], " ")
 }
 
+// matchMake returns the first of makes that the title (ignoring any year)
+// starts with, and the remainder of the title as the model.
+func matchMake(title string, makes []string) (make_, model string, ok bool) {
+	stripped := strings.TrimSpace(yearRe.ReplaceAllString(title, ""))
+	titleUpper := strings.ToUpper(stripped)
+	for _, mk := range makes {
+		if strings.HasPrefix(titleUpper, strings.ToUpper(mk)) {
+			return mk, strings.TrimSpace(stripped[len(mk):]), true
+		}
+	}
+	return "", "", false
+}
+
 func normaliseCurrency(s string) string {
 	s = strings.ToUpper(strings.TrimSpace(s))
 	switch {
@@ -282,6 +295,51 @@ func ApplyDetailFields(fields map[string]string, fullText string, l *models.List
 	}
 }
 
+// MergeImages merges detail-page gallery URLs into the card images. Gallery
+// order is kept; a card image not found in the gallery stays in front. URLs
+// that differ only by query string or size suffix are treated as the same
+// photo, and the gallery's (full-resolution) copy wins.
+func MergeImages(images, gallery []string) []string {
+	if len(gallery) == 0 {
+		return images
+	}
+
+	var merged []string
+	seen := make(map[string]bool)
+	add := func(u string) {
+		k := imageKey(u)
+		if u == "" || seen[k] {
+			return
+		}
+		seen[k] = true
+		merged = append(merged, u)
+	}
+
+	inGallery := make(map[string]bool, len(gallery))
+	for _, u := range gallery {
+		inGallery[imageKey(u)] = true
+	}
+	for _, u := range images {
+		if !inGallery[imageKey(u)] {
+			add(u)
+		}
+	}
+	for _, u := range gallery {
+		add(u)
+	}
+	return merged
+}
+
+// imageKey identifies the photo behind an image URL, ignoring scheme, query
+// string and resize suffixes.
+func imageKey(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	u = strings.TrimPrefix(strings.TrimPrefix(u, "https:"), "http:")
+	return strings.ToLower(imageSizeRe.ReplaceAllString(u, "$1"))
+}
+
 // parseMileageValue converts a raw mileage string (e.g. "Over 100,000" or
 // "48,050") into an integer, applying the same sanity bounds as ParseMileage.
 func parseMileageValue(raw string) *int {
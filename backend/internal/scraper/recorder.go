@@ -0,0 +1,169 @@
+package scraper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// SkipReason identifies why a raw card was not accepted as a listing.
+type SkipReason int
+
+const (
+	SkipEmpty          SkipReason = iota // card had neither title nor price
+	SkipNoYear                           // no model year could be parsed
+	SkipLowPrice                         // price below the category minimum
+	SkipPriceOnRequest                   // "price upon request" adverts
+)
+
+// Recorder accumulates the counters for a single scrape run. It is safe for
+// concurrent use, and all methods are no-ops on a nil *Recorder so callers
+// that don't care about run statistics can simply pass nil.
+type Recorder struct {
+	mu      sync.Mutex
+	run     models.ScrapeRun
+	blocked map[string]struct{} // URLs already counted as blocked
+}
+
+// NewRecorder returns a Recorder for a run starting now.
+func NewRecorder() *Recorder {
+	return &Recorder{run: models.ScrapeRun{
+		StartedAt: time.Now(),
+		Status:    models.RunStatusRunning,
+	}}
+}
+
+func (r *Recorder) update(fn func(run *models.ScrapeRun)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.run)
+}
+
+// PageVisited counts one listings page and the raw cards found on it.
+func (r *Recorder) PageVisited(rawCards int) {
+	r.update(func(run *models.ScrapeRun) {
+		run.PagesVisited++
+		run.RawCards += rawCards
+	})
+}
+
+// Accepted counts a card that passed all filters.
+func (r *Recorder) Accepted() {
+	r.update(func(run *models.ScrapeRun) { run.Accepted++ })
+}
+
+// Skipped counts a card rejected for the given reason.
+func (r *Recorder) Skipped(reason SkipReason) {
+	r.update(func(run *models.ScrapeRun) {
+		switch reason {
+		case SkipEmpty:
+			run.SkippedEmpty++
+		case SkipNoYear:
+			run.SkippedNoYear++
+		case SkipLowPrice:
+			run.SkippedLowPrice++
+		case SkipPriceOnRequest:
+			run.SkippedPriceOnRequest++
+		}
+	})
+}
+
+// Unchanged counts an accepted card whose detail fetch was skipped because it
+// matches the stored listing (incremental mode).
+func (r *Recorder) Unchanged() {
+	r.update(func(run *models.ScrapeRun) { run.Unchanged++ })
+}
+
+// Blocked counts a listings or detail page answered with a bot challenge.
+// Each URL is counted once per run, however often it is retried.
+func (r *Recorder) Blocked(url string) {
+	r.update(func(run *models.ScrapeRun) {
+		if _, seen := r.blocked[url]; seen {
+			return
+		}
+		if r.blocked == nil {
+			r.blocked = make(map[string]struct{})
+		}
+		r.blocked[url] = struct{}{}
+		run.BlockedPages++
+	})
+}
+
+// DetailFailed counts a detail page that could not be fetched or evaluated.
+func (r *Recorder) DetailFailed() {
+	r.update(func(run *models.ScrapeRun) { run.DetailFailures++ })
+}
+
+// EnrichCall counts one AI enrichment request and whether it failed.
+func (r *Recorder) EnrichCall(failed bool) {
+	r.update(func(run *models.ScrapeRun) {
+		run.EnrichCalls++
+		if failed {
+			run.EnrichFailures++
+		}
+	})
+}
+
+// Upserted counts one successful upsert.
+func (r *Recorder) Upserted(inserted, priceChanged bool) {
+	r.update(func(run *models.ScrapeRun) {
+		switch {
+		case inserted:
+			run.Inserted++
+		case priceChanged:
+			run.PriceChanged++
+			run.Updated++
+		default:
+			run.Updated++
+		}
+	})
+}
+
+// UpsertFailed counts one listing that could not be written to the database.
+func (r *Recorder) UpsertFailed() {
+	r.update(func(run *models.ScrapeRun) { run.UpsertErrors++ })
+}
+
+// SetComplete records whether pagination reached the natural end of results.
+func (r *Recorder) SetComplete(complete bool) {
+	r.update(func(run *models.ScrapeRun) { run.Complete = complete })
+}
+
+// SetDelisted records how many listings the delisting sweep deactivated.
+func (r *Recorder) SetDelisted(n int) {
+	r.update(func(run *models.ScrapeRun) { run.Delisted = n })
+}
+
+// Finish stamps the end time and final status. A non-nil err marks the run
+// as failed — or blocked, when it wraps ErrBlocked — and stores its message.
+func (r *Recorder) Finish(err error) {
+	r.update(func(run *models.ScrapeRun) {
+		now := time.Now()
+		run.FinishedAt = &now
+		switch {
+		case err == nil:
+			run.Status = models.RunStatusSucceeded
+		case errors.Is(err, ErrBlocked):
+			run.Status = models.RunStatusBlocked
+			run.Error = err.Error()
+		default:
+			run.Status = models.RunStatusFailed
+			run.Error = err.Error()
+		}
+	})
+}
+
+// Run returns a copy of the counters recorded so far.
+func (r *Recorder) Run() models.ScrapeRun {
+	if r == nil {
+		return models.ScrapeRun{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.run
+}
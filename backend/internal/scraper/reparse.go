@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"strings"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// String returns the short human-readable form used in logs and reports.
+func (r SkipReason) String() string {
+	switch r {
+	case SkipEmpty:
+		return "empty card"
+	case SkipNoYear:
+		return "no year"
+	case SkipLowPrice:
+		return "price too low"
+	case SkipPriceOnRequest:
+		return "price upon request"
+	default:
+		return "unknown"
+	}
+}
+
+// Qualify applies the acceptance filters used during a scrape. It returns
+// ok=false and the reason when the parsed card must be skipped.
+func Qualify(l models.Listing) (reason SkipReason, ok bool) {
+	switch {
+	case l.Title == "" && l.Price == 0:
+		return SkipEmpty, false
+	case l.Year == nil && categoryOf(l).RequireYear:
+		return SkipNoYear, false
+	case l.Price < categoryOf(l).MinPrice:
+		return SkipLowPrice, false
+	case strings.Contains(strings.ToLower(l.Title), "price upon request"):
+		return SkipPriceOnRequest, false
+	}
+	return 0, true
+}
+
+// ParseSnapshot re-runs the current parser over an archived snapshot, exactly
+// as a live scrape would have: ParseCategoryCard on the card text, then
+// ApplyDetailFields, ApplyCategoryFields and ApplyAdDetails on the detail
+// field map and page text when the detail page was captured, and MergeImages
+// on its gallery. Relative ad dates resolve against the capture time.
+func ParseSnapshot(s models.ListingSnapshot) models.Listing {
+	c := categoryOf(models.Listing{Category: s.Category})
+	l := ParseCategoryCard(c, s.CardText, s.URL, s.ImgSrc)
+	if s.DetailFields != nil || s.DetailText != "" {
+		ApplyDetailFields(s.DetailFields, s.DetailText, &l)
+		ApplyCategoryFields(c, s.DetailFields, &l)
+		seen := time.Now()
+		if s.CapturedAt != nil {
+			seen = *s.CapturedAt
+		}
+		ApplyAdDetails(s.DetailFields, seen, &l)
+	}
+	l.Images = MergeImages(l.Images, s.DetailImages)
+	return l
+}
+
+// KeepEnrichedNames carries make, model and title over from the stored
+// listing when the parser still can't recognise the make. Those values were
+// most likely set by AI enrichment, which re-parsing doesn't repeat.
+func KeepEnrichedNames(parsed, stored models.Listing) models.Listing {
+	if needsEnrichment(parsed) && !needsEnrichment(stored) {
+		parsed.Make = stored.Make
+		parsed.Model = stored.Model
+		parsed.Title = stored.Title
+	}
+	return parsed
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy retries a browser operation with exponential backoff and
+// jitter. Transient Cloudflare or network hiccups are common enough that a
+// single failed navigation must not truncate a whole run. The zero value
+// tries every operation once.
+type RetryPolicy struct {
+	Attempts int           // total tries, including the first
+	Base     time.Duration // delay before the second try
+	Max      time.Duration // cap on any single delay
+	Jitter   float64       // ± fraction applied to each delay, in [0, 1)
+}
+
+// backoff returns the delay to wait after the given failed attempt (1-based).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Base << (attempt - 1)
+	if d > p.Max || d <= 0 {
+		d = p.Max
+	}
+	if p.Jitter > 0 && p.Jitter < 1 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// do calls fn until it succeeds or the attempts are used up, sleeping with
+// backoff in between. fn is told whether this is its final attempt so that
+// best-effort steps can give up gracefully instead of failing. The last
+// error is returned, annotated with the attempt count.
+func (p RetryPolicy) do(label string, fn func(final bool) error) error {
+	attempts := max(p.Attempts, 1)
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(attempt == attempts); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		d := p.backoff(attempt)
+		log.Printf("%s: attempt %d/%d failed: %v — retrying in %v", label, attempt, attempts, err, d.Round(time.Millisecond))
+		time.Sleep(d)
+	}
+	if attempts > 1 {
+		return fmt.Errorf("%w (after %d attempts)", err, attempts)
+	}
+	return err
+}
@@ -1,6 +1,7 @@
 ﻿package scraper
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -11,23 +12,201 @@ import (
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/launcher/flags"
+	"github.com/go-rod/rod/lib/proto"
 	"github.com/go-rod/stealth"
 
 	"ecaycar/backend/models"
 )
 
-const (
-	// minPrice is the minimum listing price — applied both in the URL filter
-	// and as a code-level safety net.
-	minPrice        = 4000
-	baseListingsURL = "https://ecaytrade.com/autos-boats/autos?minprice=4000"
-	cardSelector    = `a[href*="/advert/"]`
-)
+const cardSelector = `a[href*="/advert/"]`
+
+// Result is the outcome of a scrape run.
+type Result struct {
+	// Listings holds the qualifying listings that passed all filters.
+	Listings []models.Listing
+	// Unchanged holds the external_id of qualifying cards that matched
+	// Options.Known and were not re-fetched. They are not in Listings; the
+	// caller should only mark them as seen.
+	Unchanged []string
+	// Categories reports what was seen in each crawled category, in crawl
+	// order. Categories never reached because the run stopped are absent.
+	Categories []CategoryResult
+}
+
+// CategoryResult is the outcome of paginating through one category.
+type CategoryResult struct {
+	// Category is the slug of the category crawled.
+	Category string
+	// SeenIDs holds the external_id of every advert card encountered,
+	// including cards that were filtered out. A listing that is still on
+	// the site but no longer qualifies must not be treated as delisted.
+	SeenIDs []string
+	// Complete is true only when pagination ran to the natural end of the
+	// category. Categories cut short by MAX_PAGES or a page error are
+	// partial and must not be used to deactivate unseen listings.
+	Complete bool
+}
+
+// Complete reports whether every category in the run was crawled to the end.
+func (r Result) Complete() bool {
+	if len(r.Categories) == 0 {
+		return false
+	}
+	for _, c := range r.Categories {
+		if !c.Complete {
+			return false
+		}
+	}
+	return true
+}
+
+// Options configures a scrape run. The zero value crawls the live site
+// without recording or archiving anything.
+type Options struct {
+	// Source is where pages are loaded from; nil means LiveSource().
+	Source PageSource
+	// Recorder accumulates per-run counters; may be nil.
+	Recorder *Recorder
+	// Archive, when set, receives the raw inputs of every advert card seen,
+	// including the detail page for accepted cards. It may be called from
+	// multiple goroutines.
+	Archive func(models.ListingSnapshot)
+	// Known enables incremental mode: a qualifying card whose price and card
+	// title match its entry here skips the detail fetch and is reported in
+	// Result.Unchanged. nil means every detail page is fetched.
+	Known map[string]models.KnownListing
+	// StopAfterStalePages stops pagination after that many consecutive
+	// pages with nothing new or changed. Only used with Known; 0 = never.
+	StopAfterStalePages int
+
+	// DetailWorkers is how many detail pages are fetched concurrently;
+	// values below 1 mean one.
+	DetailWorkers int
+	// DetailInterval is the minimum gap between any two detail navigations
+	// across all workers. Sources that aren't live are never paced.
+	DetailInterval time.Duration
+	// Retry is the backoff for navigations and evals.
+	Retry RetryPolicy
+}
+
+// session bundles the state shared by every page visited during one run.
+type session struct {
+	browser  *rod.Browser
+	headless bool
+	proxies  *proxyPool
+	src      PageSource
+	rec      *Recorder
+	archive  func(models.ListingSnapshot)
+	known    map[string]models.KnownListing
+
+	maxPages       int // per category; 0 = no limit
+	stopAfterStale int // incremental stop threshold; 0 = never
+
+	detailWorkers int          // concurrent detail pages
+	limiter       *rateLimiter // shared pacing for detail navigations
+	retry         RetryPolicy  // backoff for navigations and evals
+}
+
+// launch starts a browser behind the active proxy, if any, and makes it the
+// session's browser.
+func (s *session) launch() error {
+	// Leakless(false) disables the leakless.exe helper that Windows Defender
+	// incorrectly flags as malware (known false positive with go-rod on Windows).
+	l := launcher.New().Headless(s.headless).Leakless(false)
+	p := s.proxies.active()
+	if p != nil {
+		l = l.Proxy(p.server)
+		if !s.src.Live() {
+			// Chrome never proxies loopback by default; replayed pages are
+			// served on loopback, so opt in to let a local proxy see them.
+			l = l.Set(flags.Flag("proxy-bypass-list"), "<-loopback>")
+		}
+	}
+
+	u, err := l.Launch()
+	if err != nil {
+		return fmt.Errorf("launcher: %w", err)
+	}
+
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		return fmt.Errorf("browser connect: %w", err)
+	}
+	if p != nil && p.username != "" {
+		if err := handleProxyAuth(browser, p.username, p.password); err != nil {
+			_ = browser.Close()
+			return err
+		}
+	}
+	s.browser = browser
+	return nil
+}
+
+// closeBrowser shuts down the session's browser, if one is running.
+func (s *session) closeBrowser() {
+	if s.browser == nil {
+		return
+	}
+	if cerr := s.browser.Close(); cerr != nil {
+		log.Printf("browser.Close: %v", cerr)
+	}
+	s.browser = nil
+}
+
+// load navigates page to url and waits for the load event, retrying both with
+// backoff. A load event that never fires is tolerated on the final attempt —
+// some pages fire it late, which is fine as long as the content appears.
+func (s *session) load(page *rod.Page, url string, navTimeout, loadTimeout time.Duration, label string) error {
+	return s.retry.do(label, func(final bool) error {
+		if err := page.Timeout(navTimeout).Navigate(url); err != nil {
+			return fmt.Errorf("navigate: %w", err)
+		}
+		if err := page.Timeout(loadTimeout).WaitLoad(); err != nil {
+			if final {
+				log.Printf("%s WaitLoad timed out (continuing anyway): %v", label, err)
+				return nil
+			}
+			return fmt.Errorf("WaitLoad: %w", err)
+		}
+		return nil
+	})
+}
+
+// archiveSnapshot hands snap to the archive callback, if one is configured.
+func (s *session) archiveSnapshot(snap *models.ListingSnapshot) {
+	if s.archive == nil || snap.ExternalID == "" {
+		return
+	}
+	s.archive(*snap)
+}
+
+// delay returns a human-like pause of base plus up to jitter, or zero when the
+// source isn't live — replayed pages don't need pacing.
+func (s *session) delay(base, jitter time.Duration) time.Duration {
+	if !s.src.Live() {
+		return 0
+	}
+	return base + time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// Scrape launches a browser and scrapes ALL pages of every category named in
+// SCRAPE_CATEGORIES (default autos) from opts.Source, applying filters and
+// returning only qualifying listings.
+// Set MAX_PAGES env var to limit pages per category (e.g. MAX_PAGES=3 for testing).
+// If a listings page is blocked by a bot challenge, the listings scraped so
+// far are returned together with an error wrapping ErrBlocked.
+func Scrape(opts Options) (Result, error) {
+	src, rec := opts.Source, opts.Recorder
+	if src == nil {
+		src = LiveSource()
+	}
+
+	cats, err := categoriesFromEnv()
+	if err != nil {
+		return Result{}, err
+	}
 
-// Scrape launches a browser and scrapes ALL pages of the autos listing,
-// applying filters and returning only qualifying car listings.
-// Set MAX_PAGES env var to limit pages (e.g. MAX_PAGES=3 for testing).
-func Scrape() ([]models.Listing, error) {
 	headless := strings.EqualFold(os.Getenv("HEADLESS"), "true")
 	maxPages := 0 // 0 = no limit
 	if v := os.Getenv("MAX_PAGES"); v != "" {
@@ -36,150 +215,339 @@ func Scrape() ([]models.Listing, error) {
 		}
 	}
 
-	log.Printf("Launching browser (headless=%v, maxPages=%d)...", headless, maxPages)
+	detailWorkers := max(opts.DetailWorkers, 1)
+	detailInterval := opts.DetailInterval
+	if !src.Live() {
+		detailInterval = 0
+	}
 
-	// Leakless(false) disables the leakless.exe helper that Windows Defender
-	// incorrectly flags as malware (known false positive with go-rod on Windows).
-	l := launcher.New().Headless(headless).Leakless(false)
-	u, err := l.Launch()
+	stopAfterStale := 0
+	if opts.Known != nil {
+		stopAfterStale = opts.StopAfterStalePages
+	}
+
+	proxies, err := proxyPoolFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("launcher: %w", err)
+		return Result{}, err
+	}
+
+	s := &session{
+		headless:       headless,
+		proxies:        proxies,
+		src:            src,
+		rec:            rec,
+		archive:        opts.Archive,
+		known:          opts.Known,
+		maxPages:       maxPages,
+		stopAfterStale: stopAfterStale,
+		detailWorkers:  detailWorkers,
+		limiter:        newRateLimiter(detailInterval),
+		retry:          opts.Retry,
+	}
+
+	slugs := make([]string, len(cats))
+	for i, c := range cats {
+		slugs[i] = c.Slug
+	}
+	log.Printf("Launching browser (headless=%v, maxPages=%d, detailWorkers=%d, proxy=%v, categories=%s)...",
+		headless, maxPages, detailWorkers, proxies.active(), strings.Join(slugs, ","))
+	if err := s.launch(); err != nil {
+		return Result{}, err
+	}
+	defer s.closeBrowser()
+
+	var res Result
+	var retryQueue []queuedDetail
+	var runErr error // non-nil only when the run had to stop, e.g. blocked
+	for i, c := range cats {
+		if i > 0 {
+			if delay := s.delay(2000*time.Millisecond, 1500*time.Millisecond); delay > 0 {
+				log.Printf("Waiting %v before category %s...", delay, c.Slug)
+				time.Sleep(delay)
+			}
+		}
+		cr, err := s.scrapeCategory(c, &res, &retryQueue)
+		res.Categories = append(res.Categories, cr)
+		if err != nil {
+			runErr = err
+			break
+		}
 	}
 
-	browser := rod.New().ControlURL(u).MustConnect()
-	defer func() {
-		if cerr := browser.Close(); cerr != nil {
-			log.Printf("browser.Close: %v", cerr)
+	// Retrying detail pages straight after a block would only hit it again.
+	if len(retryQueue) > 0 && runErr == nil {
+		s.retryFailedDetails(&res, retryQueue)
+	} else {
+		for _, q := range retryQueue {
+			s.archiveSnapshot(&q.job.snap)
+			rec.DetailFailed()
 		}
-	}()
+	}
 
-	var allListings []models.Listing
+	rec.SetComplete(res.Complete())
+	log.Printf("Scrape complete: %d categor(ies), %d total listings, %d unchanged (complete=%v)",
+		len(res.Categories), len(res.Listings), len(res.Unchanged), res.Complete())
+	return res, runErr
+}
+
+// scrapeCategory paginates through one category, appending its listings to
+// res and its failed detail fetches to retryQueue. A page error ends the
+// category as partial; a non-nil error means the whole run must stop.
+func (s *session) scrapeCategory(c Category, res *Result, retryQueue *[]queuedDetail) (CategoryResult, error) {
+	cr := CategoryResult{Category: c.Slug}
+	seen := make(map[string]struct{})
 	pageNum := 1
+	stalePages := 0
+	rotations := 0 // proxies tried since the last successful page
+	total := 0
 
+	log.Printf("[%s] scraping category", c.Slug)
 	for {
-		if maxPages > 0 && pageNum > maxPages {
-			log.Printf("Reached MAX_PAGES=%d, stopping.", maxPages)
-			break
+		if s.maxPages > 0 && pageNum > s.maxPages {
+			log.Printf("[%s] reached MAX_PAGES=%d, stopping.", c.Slug, s.maxPages)
+			return cr, nil
 		}
 
-		url := baseListingsURL
-		if pageNum > 1 {
-			url = fmt.Sprintf("%s&page=%d", baseListingsURL, pageNum)
+		url, ok := s.src.ListingsURL(c, pageNum)
+		if !ok {
+			log.Printf("[%s page %d] source has no more pages — end of listings", c.Slug, pageNum)
+			cr.Complete = pageNum > 1
+			return cr, nil
 		}
 
-		listings, hasNext, rawCount, err := scrapePage(browser, url, pageNum)
+		pr, err := s.scrapePage(c, url, pageNum)
+		if errors.Is(err, ErrBlocked) && s.proxies.rotate(rotations) {
+			rotations++
+			log.Printf("[%s page %d] %v — rotating to proxy %v", c.Slug, pageNum, err, s.proxies.active())
+			s.closeBrowser()
+			if err := s.launch(); err != nil {
+				return cr, fmt.Errorf("%s page %d: relaunch after block: %w", c.Slug, pageNum, err)
+			}
+			continue
+		}
+		if errors.Is(err, ErrBlocked) {
+			log.Printf("[%s page %d] %v — stopping pagination", c.Slug, pageNum, err)
+			return cr, fmt.Errorf("%s page %d: %w", c.Slug, pageNum, err)
+		}
 		if err != nil {
-			log.Printf("[page %d] error: %v — stopping pagination", pageNum, err)
-			break
+			log.Printf("[%s page %d] error: %v — stopping pagination", c.Slug, pageNum, err)
+			return cr, nil
 		}
-		// Stop only when the page returned zero raw cards (true end of listings).
+		rotations = 0
+
+		// Stop only when the page rendered but held zero raw cards (true end
+		// of listings); a page whose cards never appeared failed above.
 		// A page full of filtered-out parts is not a stopping condition.
-		if rawCount == 0 {
-			log.Printf("[page %d] no raw cards found — end of listings", pageNum)
-			break
+		// An empty first page is never a complete run.
+		if pr.rawCount == 0 {
+			log.Printf("[%s page %d] no raw cards found — end of listings", c.Slug, pageNum)
+			cr.Complete = pageNum > 1
+			return cr, nil
 		}
 
-		allListings = append(allListings, listings...)
-		log.Printf("[page %d] accepted %d listing(s) — total so far: %d", pageNum, len(listings), len(allListings))
+		for _, id := range pr.seenIDs {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				cr.SeenIDs = append(cr.SeenIDs, id)
+			}
+		}
+		for _, f := range pr.failed {
+			*retryQueue = append(*retryQueue, queuedDetail{pos: len(res.Listings) + f.pos, job: f.job})
+		}
+		res.Listings = append(res.Listings, pr.listings...)
+		res.Unchanged = append(res.Unchanged, pr.unchanged...)
+		total += len(pr.listings) + len(pr.unchanged)
+		log.Printf("[%s page %d] accepted %d listing(s), %d unchanged — total so far: %d",
+			c.Slug, pageNum, len(pr.listings), len(pr.unchanged), total)
+
+		if !pr.hasNext {
+			log.Printf("[%s page %d] no next page found — done", c.Slug, pageNum)
+			cr.Complete = true
+			return cr, nil
+		}
 
-		if !hasNext {
-			log.Printf("[page %d] no next page found — done", pageNum)
-			break
+		// Stopping early leaves the category partial, so no delisting sweep follows.
+		if len(pr.listings) == 0 && len(pr.unchanged) > 0 {
+			stalePages++
+		} else {
+			stalePages = 0
+		}
+		if s.stopAfterStale > 0 && stalePages >= s.stopAfterStale {
+			log.Printf("[%s page %d] %d consecutive page(s) with nothing new — stopping (incremental)", c.Slug, pageNum, stalePages)
+			return cr, nil
 		}
 
 		pageNum++
 
 		// Human-like delay between pages (2–3.5 s)
-		delay := time.Duration(2000+rand.Intn(1500)) * time.Millisecond
-		log.Printf("Waiting %v before page %d...", delay, pageNum)
-		time.Sleep(delay)
+		if delay := s.delay(2000*time.Millisecond, 1500*time.Millisecond); delay > 0 {
+			log.Printf("Waiting %v before page %d...", delay, pageNum)
+			time.Sleep(delay)
+		}
 	}
+}
 
-	log.Printf("Scrape complete: %d pages, %d total listings", pageNum, len(allListings))
-	return allListings, nil
+// pageResult holds everything scrapePage learned from a single listings page.
+type pageResult struct {
+	listings  []models.Listing // filtered listings with detail fields applied
+	unchanged []string         // external IDs of known, unchanged listings
+	failed    []queuedDetail   // listings whose detail fetch failed; pos indexes listings
+	seenIDs   []string         // external IDs of every raw card, filtered or not
+	hasNext   bool             // whether a link to the next page exists
+	rawCount  int              // raw (unfiltered) card count
 }
 
-// scrapePage scrapes a single URL and returns filtered listings, whether
-// a next page exists, and the raw (unfiltered) card count.
-func scrapePage(browser *rod.Browser, url string, pageNum int) (listings []models.Listing, hasNext bool, rawCount int, err error) {
-	page, err := stealth.Page(browser)
+// scrapePage scrapes a single listings page of category c and returns filtered
+// listings, the IDs of every card seen, whether a next page exists, and the
+// raw card count. A page whose listing cards never appear is an error, not
+// an empty page.
+func (s *session) scrapePage(c Category, url string, pageNum int) (pageResult, error) {
+	var pr pageResult
+	rec := s.rec
+
+	page, err := stealth.Page(s.browser)
 	if err != nil {
-		return nil, false, 0, fmt.Errorf("stealth.Page: %w", err)
+		return pr, fmt.Errorf("stealth.Page: %w", err)
 	}
 	defer func() { _ = page.Close() }()
 
 	log.Printf("[page %d] navigating to %s", pageNum, url)
-	if err = page.Navigate(url); err != nil {
-		return nil, false, 0, fmt.Errorf("navigate: %w", err)
-	}
-	// WaitLoad with a graceful timeout — some pages fire load late, which is fine
-	// as long as the card selector appears afterwards.
-	if werr := page.Timeout(20 * time.Second).WaitLoad(); werr != nil {
-		log.Printf("[page %d] WaitLoad timed out (continuing anyway): %v", pageNum, werr)
+	if err = s.load(page, url, 30*time.Second, 20*time.Second, fmt.Sprintf("[page %d]", pageNum)); err != nil {
+		return pr, err
 	}
 
 	if werr := waitForSelector(page, cardSelector, 30*time.Second); werr != nil {
+		rec.PageVisited(0)
+		// Only probe for a challenge once cards fail to appear: stealth often
+		// clears a Cloudflare interstitial on its own within a few seconds.
+		if berr := detectBlock(page); berr != nil {
+			rec.Blocked(url)
+			return pr, berr
+		}
 		html, _ := page.HTML()
 		log.Printf("[page %d] listing cards never appeared — HTML dump:\n%s", pageNum, truncate(html, 3000))
-		return nil, false, 0, nil // treat as empty page, not a fatal error
+		// A timeout or markup change is not the end of the listings: failing
+		// the page keeps the category partial so no delisting sweep follows.
+		return pr, fmt.Errorf("listing cards never appeared: %w", werr)
 	}
 
 	// Human-like pause before extracting
-	delay := time.Duration(1500+rand.Intn(1000)) * time.Millisecond
-	log.Printf("[page %d] sleeping %v...", pageNum, delay)
-	time.Sleep(delay)
+	if delay := s.delay(1500*time.Millisecond, 1000*time.Millisecond); delay > 0 {
+		log.Printf("[page %d] sleeping %v...", pageNum, delay)
+		time.Sleep(delay)
+	}
 
-	cards, err := extractCards(page)
+	var cards []rawCard
+	err = s.retry.do(fmt.Sprintf("[page %d] extractCards", pageNum), func(bool) error {
+		cards, err = extractCards(page)
+		return err
+	})
 	if err != nil {
-		return nil, false, 0, fmt.Errorf("extractCards: %w", err)
+		return pr, fmt.Errorf("extractCards: %w", err)
+	}
+	for i := range cards {
+		cards[i].URL = s.src.CanonicalURL(cards[i].URL)
 	}
-	rawCount = len(cards)
-	log.Printf("[page %d] found %d raw card(s)", pageNum, rawCount)
+	pr.rawCount = len(cards)
+	rec.PageVisited(pr.rawCount)
+	log.Printf("[page %d] found %d raw card(s)", pageNum, pr.rawCount)
 
 	// Detect next page: look for a link to page N+1
-	hasNext = hasNextPage(page, pageNum)
+	pr.hasNext = hasNextPage(page, pageNum)
+
+	for _, card := range cards {
+		if m := idRe.FindStringSubmatch(card.URL); len(m) == 2 {
+			pr.seenIDs = append(pr.seenIDs, m[1])
+		}
+	}
 
-	for i, c := range cards {
+	jobs := make([]*cardJob, len(cards))
+	for i, card := range cards {
+		j := &cardJob{idx: i, page: pageNum}
+		jobs[i] = j
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
 					log.Printf("[page %d / card %d] panic: %v", pageNum, i, r)
+					j.dropped = true
 				}
 			}()
-			l := ParseCard(c.Text, c.URL, c.ImgSrc)
-			if l.Title == "" && l.Price == 0 {
-				return
-			}
-			if l.Year == nil {
-				log.Printf("[page %d / card %d] skip — no year: %s", pageNum, i, l.Title)
-				return
+			j.snap = models.ListingSnapshot{Category: c.Slug, URL: card.URL, CardText: card.Text, ImgSrc: card.ImgSrc}
+			if m := idRe.FindStringSubmatch(card.URL); len(m) == 2 {
+				j.snap.ExternalID = m[1]
 			}
-			if l.Price < minPrice {
-				log.Printf("[page %d / card %d] skip — price too low (CI$%.0f): %s", pageNum, i, l.Price, l.Title)
+
+			j.listing = ParseCategoryCard(c, card.Text, card.URL, card.ImgSrc)
+			if reason, ok := Qualify(j.listing); !ok {
+				l := j.listing
+				switch reason {
+				case SkipNoYear:
+					log.Printf("[page %d / card %d] skip — no year: %s", pageNum, i, l.Title)
+				case SkipLowPrice:
+					log.Printf("[page %d / card %d] skip — price too low (CI$%.0f): %s", pageNum, i, l.Price, l.Title)
+				case SkipPriceOnRequest:
+					log.Printf("[page %d / card %d] skip — price upon request", pageNum, i)
+				}
+				rec.Skipped(reason)
 				return
 			}
-			if strings.Contains(strings.ToLower(l.Title), "price upon request") {
-				log.Printf("[page %d / card %d] skip — price upon request", pageNum, i)
+			rec.Accepted()
+			if k, ok := s.known[j.listing.ExternalID]; ok &&
+				k.Price == j.listing.Price && k.Title == j.listing.CardTitle {
+				rec.Unchanged()
+				j.unchanged = true
 				return
 			}
-			// Always fetch the detail page to capture all structured fields.
-			fetchAndApplyDetailFields(browser, &l, pageNum, i)
-			listings = append(listings, l)
+			j.accepted = true
 		}()
 	}
 
-	return listings, hasNext, rawCount, nil
+	// Always fetch the detail page to capture all structured fields.
+	s.fetchDetails(jobs)
+
+	for _, j := range jobs {
+		// Archive every card, filtered or not: a parser fix may turn a
+		// skipped card into a valid listing when history is re-parsed.
+		// Cards queued for a second detail pass are archived once that
+		// pass is over, so each card gets one snapshot per run.
+		queued := j.accepted && !j.dropped && j.detailErr != nil
+		if !queued {
+			s.archiveSnapshot(&j.snap)
+		}
+
+		if j.unchanged {
+			pr.unchanged = append(pr.unchanged, j.snap.ExternalID)
+			continue
+		}
+		if !j.accepted || j.dropped {
+			continue
+		}
+		// A failed fetch still keeps the card-level listing, marked CardOnly
+		// so its stored detail fields survive; the detail page gets a second
+		// pass at the end of the run.
+		l := j.listing
+		if queued {
+			log.Printf("[page %d / card %d] %v — queued for second pass", pageNum, j.idx, j.detailErr)
+			pr.failed = append(pr.failed, queuedDetail{pos: len(pr.listings), job: j})
+			l.CardOnly = true
+		}
+		pr.listings = append(pr.listings, l)
+	}
+
+	return pr, nil
 }
 
 // detailJS is the JavaScript injected into each listing detail page.
 // It extracts key→value pairs from the "Ad Details" section using three
 // strategies (DOM structure, dt/dd pairs, line-by-line text), ensuring the
-// best possible coverage regardless of minor HTML changes.
-const detailJS = `() => {
+// best possible coverage regardless of minor HTML changes. Its argument is
+// the category's extra detail labels.
+const detailJS = `(extra) => {
 const known = new Set([
   'body type','cylinders','make','drive','fuel type','transmission',
   'steering','exterior color','interior color','doors','on island',
-  'condition','mileage','year'
+  'condition','mileage','year',
+  ...(extra || [])
 ]);
 const fields = {};
 
@@ -228,29 +596,67 @@ for (let i = 0; i < lines.length - 1; i++) {
 return fields;
 }`
 
-// fetchAndApplyDetailFields opens the listing detail page, runs detailJS to
-// extract an "Ad Details" field map, then calls ApplyDetailFields to merge
-// the result into the listing struct.
-func fetchAndApplyDetailFields(browser *rod.Browser, l *models.Listing, pageNum, cardIdx int) {
-	page, err := stealth.Page(browser)
-	if err != nil {
-		log.Printf("[page %d / card %d] detail stealth.Page: %v", pageNum, cardIdx, err)
-		return
-	}
-	defer func() { _ = page.Close() }()
+// galleryJS collects the photo gallery of a listing detail page as absolute
+// URLs in page order. For each image it prefers the full-resolution source:
+// a zoom/lazy-load data attribute, a wrapping link to the image file, the
+// largest srcset candidate, then src. Icons, avatars and the thumbnails of
+// other adverts are skipped. Duplicates are removed by MergeImages.
+const galleryJS = `() => {
+const urls = [];
+const largest = (srcset) => {
+  let best = '', bestW = 0;
+  for (const part of (srcset || '').split(',')) {
+    const [u, d] = part.trim().split(/\s+/);
+    const w = parseFloat(d) || 1;
+    if (u && w >= bestW) { best = u; bestW = w; }
+  }
+  return best;
+};
+const root = document.querySelector('main') || document.body;
+for (const img of root.querySelectorAll('img')) {
+  if (img.closest('header, footer, nav, a[href*="/advert/"]')) continue;
+  const link = img.closest('a[href]');
+  const linked = link && /\.(jpe?g|png|webp)(\?|$)/i.test(link.href) ? link.href : '';
+  const lazy = img.dataset.zoomImage || img.dataset.full || img.dataset.large || img.dataset.src || '';
+  if (!linked && !lazy && img.naturalWidth && img.naturalWidth < 120) continue;
+  let u = lazy || linked || largest(img.getAttribute('srcset')) || img.currentSrc || img.src;
+  try { u = new URL(u, location.href).href; } catch (e) { continue; }
+  if (!/^https?:/.test(u) || /\.svg(\?|$)/i.test(u)) continue;
+  urls.push(u);
+}
+return urls;
+}`
 
-	if err := page.Timeout(20 * time.Second).Navigate(l.URL); err != nil {
-		log.Printf("[page %d / card %d] detail navigate: %v", pageNum, cardIdx, err)
-		return
-	}
-	if werr := page.Timeout(10 * time.Second).WaitLoad(); werr != nil {
-		log.Printf("[page %d / card %d] detail WaitLoad (continuing): %v", pageNum, cardIdx, werr)
+// fetchAndApplyDetailFields loads the listing detail page into page, runs
+// detailJS to extract an "Ad Details" field map, then calls ApplyDetailFields
+// and ApplyCategoryFields to merge the result into the listing struct. The
+// ad's description, seller and dates (adMetaJS) are applied by
+// ApplyAdDetails, and gallery images found by galleryJS are merged into
+// l.Images. A non-nil error means the detail fields could not be extracted;
+// l is left with its card-level values. The raw page is captured into snap
+// when archiving is enabled.
+func (s *session) fetchAndApplyDetailFields(page *rod.Page, l *models.Listing, snap *models.ListingSnapshot, pageNum, cardIdx int) error {
+	label := fmt.Sprintf("[page %d / card %d] detail", pageNum, cardIdx)
+	url := s.src.DetailURL(l.URL)
+	if err := s.load(page, url, 20*time.Second, 10*time.Second, label); err != nil {
+		return fmt.Errorf("detail %w", err)
 	}
 
-	res, err := page.Eval(detailJS)
+	c := categoryOf(*l)
+	var res *proto.RuntimeRemoteObject
+	err := s.retry.do(label+" JS eval", func(bool) error {
+		var err error
+		res, err = page.Eval(detailJS, c.detailLabels())
+		return err
+	})
 	if err != nil {
-		log.Printf("[page %d / card %d] detail JS eval: %v", pageNum, cardIdx, err)
-		return
+		return fmt.Errorf("detail JS eval: %w", err)
+	}
+	if len(res.Value.Map()) == 0 {
+		if berr := detectBlock(page); berr != nil {
+			s.rec.Blocked(url)
+			return fmt.Errorf("detail: %w", berr)
+		}
 	}
 
 	// Convert the JS object into a Go map[string]string.
@@ -259,19 +665,54 @@ func fetchAndApplyDetailFields(browser *rod.Browser, l *models.Listing, pageNum,
 		fields[k] = v.Str()
 	}
 
+	// Description, seller and dates ride along in the same map so they are
+	// archived with the snapshot.
+	if meta, err := page.Eval(adMetaJS); err == nil {
+		for k, v := range meta.Value.Map() {
+			if _, ok := fields[k]; !ok {
+				fields[k] = v.Str()
+			}
+		}
+	} else {
+		log.Printf("%s ad meta JS eval: %v", label, err)
+	}
+
 	// Also grab full body text as fallback for mileage regex.
 	var fullText string
 	if txt, err := page.Eval(`() => document.body.innerText`); err == nil {
 		fullText = txt.Value.Str()
 	}
 
+	// A missing gallery is not worth failing the detail fetch over; the card
+	// thumbnail is kept either way.
+	var gallery []string
+	if g, err := page.Eval(galleryJS); err == nil {
+		for _, v := range g.Value.Arr() {
+			gallery = append(gallery, v.Str())
+		}
+	} else {
+		log.Printf("%s gallery JS eval: %v", label, err)
+	}
+
+	snap.DetailFields = fields
+	snap.DetailText = fullText
+	snap.DetailImages = gallery
+	if s.archive != nil {
+		if html, err := page.HTML(); err == nil {
+			snap.DetailHTML = html
+		}
+	}
+
 	ApplyDetailFields(fields, fullText, l)
+	ApplyCategoryFields(c, fields, l)
+	ApplyAdDetails(fields, time.Now(), l)
+	l.Images = MergeImages(l.Images, gallery)
 
-	log.Printf("[page %d / card %d] detail fields — mileage:%v bodyType:%q drive:%q cylinders:%q steering:%q onIsland:%v",
-		pageNum, cardIdx, l.Mileage, l.BodyType, l.Drive, l.Cylinders, l.Steering, l.OnIsland)
+	log.Printf("[page %d / card %d] detail fields — mileage:%v bodyType:%q drive:%q cylinders:%q steering:%q onIsland:%v attributes:%v images:%d seller:%q posted:%v",
+		pageNum, cardIdx, l.Mileage, l.BodyType, l.Drive, l.Cylinders, l.Steering, l.OnIsland, l.Attributes, len(l.Images),
+		l.SellerName, l.PostedAt)
 
-	// Brief human-like pause.
-	time.Sleep(time.Duration(400+rand.Intn(400)) * time.Millisecond)
+	return nil
 }
 
 // hasNextPage checks whether a link to the next page number exists in the DOM.
@@ -360,18 +801,3 @@ func truncate(s string, n int) string {
 	}
 	return s[:n] + "..."
 }
-
-// ---------------------------------------------------------------------------
-// PROXY FALLBACK NOTE
-// ---------------------------------------------------------------------------
-// If Cloudflare blocks the request even with stealth enabled, route traffic
-// through a residential proxy service such as ScrapingBee:
-//
-//   l = launcher.New().
-//       Headless(true).
-//       Proxy("http://YOUR_SCRAPINGBEE_PROXY_HOST:PORT").
-//       Set("--ignore-certificate-errors")
-//
-// ScrapingBee also offers a simple HTTP API (no browser required):
-//   https://www.scrapingbee.com/documentation/
-// ---------------------------------------------------------------------------
@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"regexp"
+	"strings"
+
+	"ecaycar/backend/models"
+)
+
+// dealerMinListings is the number of listings (active or not) at which a
+// seller is treated as a dealer on volume alone. Private sellers rarely have
+// more than one or two vehicles up at a time.
+const dealerMinListings = 5
+
+var (
+	// dealerNameRe matches business words in a seller's display name.
+	dealerNameRe = regexp.MustCompile(`(?i)\b(motors?|autos?|automotive|auto\s*sales|cars|car\s*sales|dealers?|dealership|garage|imports?|trading|rentals?|marine|boats|ltd|limited|inc|llc|co|company|group|enterprises?)\b\.?`)
+
+	// dealerMarkerRe matches profile badges and URL paths that only business
+	// accounts get.
+	dealerMarkerRe = regexp.MustCompile(`(?i)(dealer|dealership|business|store|shop|showroom)`)
+)
+
+// SellersFromListings collects the distinct sellers of the given listings,
+// keyed by profile URL. The last non-empty name and the union of profile
+// markers win. Listings without a seller profile are ignored.
+func SellersFromListings(listings []models.Listing) []models.Seller {
+	var sellers []models.Seller
+	index := make(map[string]int)
+	for _, l := range listings {
+		if l.SellerURL == "" {
+			continue
+		}
+		i, ok := index[l.SellerURL]
+		if !ok {
+			i = len(sellers)
+			index[l.SellerURL] = i
+			sellers = append(sellers, models.Seller{ProfileURL: l.SellerURL})
+		}
+		s := &sellers[i]
+		if l.SellerName != "" {
+			s.Name = l.SellerName
+		}
+		for _, m := range l.SellerMarkers {
+			if !containsFold(s.ProfileMarkers, m) {
+				s.ProfileMarkers = append(s.ProfileMarkers, m)
+			}
+		}
+	}
+	return sellers
+}
+
+// ClassifySeller labels a seller as a dealer or a private seller. Any one of
+// these makes a dealer: a dealer badge or store-style profile URL, a business
+// name, or at least dealerMinListings listings. Everyone else is private.
+func ClassifySeller(s models.Seller) string {
+	for _, m := range s.ProfileMarkers {
+		if dealerMarkerRe.MatchString(m) {
+			return models.SellerTypeDealer
+		}
+	}
+	if path := profilePath(s.ProfileURL); dealerMarkerRe.MatchString(path) {
+		return models.SellerTypeDealer
+	}
+	if dealerNameRe.MatchString(s.Name) {
+		return models.SellerTypeDealer
+	}
+	if s.ListingCount >= dealerMinListings {
+		return models.SellerTypeDealer
+	}
+	return models.SellerTypePrivate
+}
+
+// profilePath strips the scheme and host from a profile URL so a host name
+// can't trip the marker match.
+func profilePath(u string) string {
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+		if j := strings.IndexByte(u, '/'); j >= 0 {
+			return u[j:]
+		}
+		return ""
+	}
+	return u
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+)
+
+// siteURL is the canonical origin of ecaytrade.com listing URLs.
+const siteURL = "https://ecaytrade.com"
+
+// PageSource tells the scraper where listing and detail pages are loaded from.
+// The same extraction pipeline (extractCards, detailJS, ParseCard,
+// ApplyDetailFields) runs against every source.
+type PageSource interface {
+	// ListingsURL returns the URL of the given 1-based listings page of
+	// category c, or ok=false when the source knows there is no such page.
+	ListingsURL(c Category, page int) (url string, ok bool)
+	// CanonicalURL maps a card's href to the listing URL stored in the DB.
+	CanonicalURL(cardURL string) string
+	// DetailURL maps a canonical listing URL to the URL the browser loads.
+	DetailURL(listingURL string) string
+	// Live reports whether pages come from the real site. Human-like delays
+	// are only applied to live sources.
+	Live() bool
+	// Close releases any resources held by the source.
+	Close() error
+}
+
+// LiveSource returns the PageSource that crawls ecaytrade.com directly.
+func LiveSource() PageSource { return liveSource{} }
+
+type liveSource struct{}
+
+func (liveSource) ListingsURL(c Category, page int) (string, bool) {
+	if page > 1 {
+		return fmt.Sprintf("%s&page=%d", c.listingsURL(), page), true
+	}
+	return c.listingsURL(), true
+}
+
+func (liveSource) CanonicalURL(cardURL string) string { return cardURL }
+func (liveSource) DetailURL(listingURL string) string { return listingURL }
+func (liveSource) Live() bool                         { return true }
+func (liveSource) Close() error                       { return nil }
+
+// ReplaySource serves archived ecaytrade pages from a local directory through
+// an in-process HTTP server so scrapes are deterministic and need no network.
+// The directory layout is:
+//
+//	<dir>/listings/<category>/1.html, 2.html, …   listings pages in crawl order
+//	<dir>/advert/<external_id>.html               detail pages
+//
+// Archives recorded before categories existed keep autos pages directly in
+// <dir>/listings/; they are still served for the autos category.
+//
+// Pages should be saved as rendered DOM (document.documentElement.outerHTML).
+// Scripts embedded in the archive are blocked so they cannot re-render or
+// fetch from the live site.
+type ReplaySource struct {
+	dir string
+	srv *httptest.Server
+}
+
+// NewReplaySource starts a local server over dir. The caller must Close it.
+func NewReplaySource(dir string) (*ReplaySource, error) {
+	if fi, err := os.Stat(filepath.Join(dir, "listings")); err != nil || !fi.IsDir() {
+		return nil, fmt.Errorf("replay: %s has no listings/ directory", dir)
+	}
+
+	files := http.FileServer(http.Dir(dir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "script-src 'none'")
+		files.ServeHTTP(w, r)
+	}))
+	return &ReplaySource{dir: dir, srv: srv}, nil
+}
+
+func (s *ReplaySource) ListingsURL(c Category, page int) (string, bool) {
+	name := fmt.Sprintf("%d.html", page)
+	rel := c.Slug + "/" + name
+	if _, err := os.Stat(filepath.Join(s.dir, "listings", c.Slug)); err != nil && c.Slug == DefaultCategory {
+		rel = name
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, "listings", filepath.FromSlash(rel))); err != nil {
+		return "", false
+	}
+	return s.srv.URL + "/listings/" + rel, true
+}
+
+// CanonicalURL rewrites hrefs resolved against the local server back to
+// ecaytrade.com so replayed listings carry the same URLs as live ones.
+func (s *ReplaySource) CanonicalURL(cardURL string) string {
+	if m := idRe.FindStringSubmatch(cardURL); len(m) == 2 {
+		return siteURL + "/advert/" + m[1]
+	}
+	return cardURL
+}
+
+func (s *ReplaySource) DetailURL(listingURL string) string {
+	if m := idRe.FindStringSubmatch(listingURL); len(m) == 2 {
+		return s.srv.URL + "/advert/" + m[1] + ".html"
+	}
+	return listingURL
+}
+
+func (s *ReplaySource) Live() bool { return false }
+
+func (s *ReplaySource) Close() error {
+	s.srv.Close()
+	return nil
+}
@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-rod/rod/lib/defaults"
+	"github.com/go-rod/rod/lib/launcher"
+)
+
+// requireBrowser skips tests that drive a real browser when none is
+// installed. Point rod at a specific binary with
+// go test ./internal/scraper -args -rod=bin=/path/to/chrome.
+func requireBrowser(t *testing.T) {
+	t.Helper()
+	if defaults.Bin != "" {
+		return
+	}
+	if _, ok := launcher.LookPath(); !ok {
+		t.Skip("no Chrome/Chromium found; pass -args -rod=bin=<path> to run browser tests")
+	}
+}
+
+// replayEnv pins the environment Scrape reads so fixture runs are fast and
+// deterministic.
+func replayEnv(t *testing.T) {
+	t.Setenv("HEADLESS", "true")
+	t.Setenv("SCRAPE_CATEGORIES", "autos")
+	t.Setenv("MAX_PAGES", "")
+	t.Setenv("SCRAPER_PROXY_URL", "")
+	t.Setenv("SCRAPER_PROXY_LIST", "")
+}
+
+func TestScrapeReplay(t *testing.T) {
+	requireBrowser(t)
+
+	tests := []struct {
+		name         string
+		maxPages     string
+		wantIDs      []string
+		wantSeen     []string
+		wantComplete bool
+	}{
+		{
+			name:         "all pages",
+			wantIDs:      []string{"1001", "1002", "1004"},
+			wantSeen:     []string{"1001", "1002", "1003", "1004"},
+			wantComplete: true,
+		},
+		{
+			name:         "max pages",
+			maxPages:     "1",
+			wantIDs:      []string{"1001", "1002"},
+			wantSeen:     []string{"1001", "1002", "1003"},
+			wantComplete: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayEnv(t)
+			t.Setenv("MAX_PAGES", tt.maxPages)
+
+			src, err := NewReplaySource("testdata/replay")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer src.Close()
+
+			res, err := Scrape(Options{Source: src})
+			if err != nil {
+				t.Fatalf("Scrape: %v", err)
+			}
+
+			var ids []string
+			for _, l := range res.Listings {
+				ids = append(ids, l.ExternalID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("listings = %v, want %v", ids, tt.wantIDs)
+			}
+			if len(res.Categories) != 1 {
+				t.Fatalf("categories = %d, want 1", len(res.Categories))
+			}
+			if got := res.Categories[0].SeenIDs; !slices.Equal(got, tt.wantSeen) {
+				t.Errorf("seen = %v, want %v", got, tt.wantSeen)
+			}
+			if res.Complete() != tt.wantComplete {
+				t.Errorf("Complete() = %v, want %v", res.Complete(), tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestScrapeReplayDetailFields(t *testing.T) {
+	requireBrowser(t)
+	replayEnv(t)
+
+	src, err := NewReplaySource("testdata/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	res, err := Scrape(Options{Source: src})
+	if err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if len(res.Listings) == 0 {
+		t.Fatal("no listings scraped")
+	}
+
+	l := res.Listings[0]
+	if l.URL != siteURL+"/advert/1001" {
+		t.Errorf("URL = %q, want canonical ecaytrade URL", l.URL)
+	}
+	if l.Title != "2018 Toyota Camry SE" || l.Make != "Toyota" || l.Price != 15000 {
+		t.Errorf("card fields = %q %q %v", l.Title, l.Make, l.Price)
+	}
+	if l.Year == nil || *l.Year != 2018 {
+		t.Errorf("Year = %v, want 2018", l.Year)
+	}
+	if l.Mileage == nil || *l.Mileage != 48050 {
+		t.Errorf("Mileage = %v, want 48050", l.Mileage)
+	}
+	if l.BodyType != "Sedan" || l.Transmission != "Automatic" {
+		t.Errorf("detail fields = body %q, transmission %q", l.BodyType, l.Transmission)
+	}
+}
@@ -0,0 +1,68 @@
+package valuation
+
+import (
+	"math"
+
+	"ecaycar/backend/models"
+)
+
+// Deal score thresholds: the lowest score that earns each label. Scores below
+// fairDealScore are labelled high.
+const (
+	greatDealScore = 80
+	goodDealScore  = 60
+	fairDealScore  = 20
+)
+
+// DealScore rates l's asking price against the price the Estimator expects
+// for its make, model, year, mileage, body type and condition. The score is
+// the chance that a comparable listing would be priced higher, from the
+// model's prediction interval, as a percentile. ok is false for listings
+// without a model year or price, for those only a segment or market-wide
+// model covers, whose expected price is too rough to judge a deal by, and
+// when the score is not a finite number.
+func (e *Estimator) DealScore(l models.Listing) (score models.DealScore, ok bool) {
+	if l.Year == nil || l.Price <= 0 {
+		return score, false
+	}
+	q := e.withBodyType(queryOf(l))
+	g := e.pick(q)
+	if g == nil || (g.level != models.ValuationLevelMakeModel && g.level != models.ValuationLevelMake) {
+		return score, false
+	}
+
+	logPrice, stdErr := g.predict(q)
+	z := (math.Log(l.Price) - logPrice) / stdErr
+	if math.IsNaN(z) || math.IsInf(z, 0) {
+		// Cannot happen while sigma is floored, but a NaN score would be
+		// labelled high and break the JSON encoding of the listing.
+		return score, false
+	}
+	score = models.DealScore{
+		ListingID:     l.ID,
+		ExpectedPrice: math.Round(math.Exp(logPrice)),
+		Score:         math.Round(1000*0.5*math.Erfc(z/math.Sqrt2)) / 10,
+	}
+	switch {
+	case score.Score >= greatDealScore:
+		score.Label = models.DealGreat
+	case score.Score >= goodDealScore:
+		score.Label = models.DealGood
+	case score.Score >= fairDealScore:
+		score.Label = models.DealFair
+	default:
+		score.Label = models.DealHigh
+	}
+	return score, true
+}
+
+// ScoreDeals returns the deal score of every listing DealScore can rate.
+func (e *Estimator) ScoreDeals(listings []models.Listing) []models.DealScore {
+	var scores []models.DealScore
+	for _, l := range listings {
+		if s, ok := e.DealScore(l); ok {
+			scores = append(scores, s)
+		}
+	}
+	return scores
+}
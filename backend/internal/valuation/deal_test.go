@@ -0,0 +1,41 @@
+package valuation
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+func TestDealScoreConstantPriceGroup(t *testing.T) {
+	samples := constantPriceListings(10)
+	e := Fit(samples, time.Now())
+
+	for _, tt := range []struct {
+		price float64
+		label string
+	}{
+		{12000, models.DealFair},
+		{9000, models.DealGreat},
+		{16000, models.DealHigh},
+	} {
+		l := samples[0]
+		l.Price = tt.price
+		score, ok := e.DealScore(l)
+		if !ok {
+			t.Fatalf("price %g: not scored", tt.price)
+		}
+		if math.IsNaN(score.Score) || math.IsInf(score.Score, 0) {
+			t.Fatalf("price %g: score = %g, want a finite number", tt.price, score.Score)
+		}
+		if score.Label != tt.label {
+			t.Errorf("price %g: label = %q (score %g), want %q", tt.price, score.Label, score.Score, tt.label)
+		}
+	}
+
+	if _, err := json.Marshal(e.ScoreDeals(samples)); err != nil {
+		t.Errorf("encode scores: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package valuation
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+
+	"ecaycar/backend/models"
+)
+
+// Regression tuning.
+const (
+	// minSamples is the fewest listings a group is fitted on; smaller groups
+	// fall back to a less specific level.
+	minSamples = 8
+	// minDummySamples is the fewest listings before body type and condition
+	// get columns of their own; smaller groups use year and mileage only.
+	minDummySamples = 20
+	// minCategorySamples is the fewest listings a body type or condition
+	// needs to get a column; rarer values are treated as unknown.
+	minCategorySamples = 3
+	// ridge is the L2 penalty on every coefficient but the intercept. It
+	// keeps small groups from fitting noise.
+	ridge = 1.0
+	// z90 is the normal quantile of a two-sided 90% interval.
+	z90 = 1.645
+	// minSigma floors the residual standard error of log price, about 5%
+	// of the price, so a group whose listings fit exactly (e.g. all at one
+	// price) still gets a prediction interval.
+	minSigma = 0.05
+	// milesPerYear weighs mileage against model year when picking
+	// comparables: this many miles apart counts like one model year.
+	milesPerYear   = 15000
+	maxComparables = 5
+)
+
+// group is a regression of log price fitted over one set of listings:
+//
+//	log(price) ~ year + log(1 + mileage/1000) [+ body type + condition]
+//
+// Year and mileage are centred on the group's means, and an unknown mileage
+// is taken to be the mean.
+type group struct {
+	level     string
+	samples   []models.Listing
+	coef      []float64
+	inv       [][]float64 // (XᵀX + λI)⁻¹, for the leverage of a query
+	sigma     float64     // residual standard error of log price
+	meanYear  float64
+	meanMiles float64 // mean of logMiles over listings with a mileage
+	dummies   []dummy
+	known     map[category]bool // values with a column, plus each baseline
+}
+
+// category is one body type (body true) or condition value, normalized.
+type category struct {
+	body  bool
+	value string
+}
+
+// dummy is an indicator column for one category. A listing or query whose
+// value for that field is unknown gets mean, the share of the group's
+// listings with a known value that have this one.
+type dummy struct {
+	category
+	mean float64
+}
+
+// newGroup fits a group over samples, which all have a year and a positive
+// price. It returns nil when there are fewer than minSamples.
+func newGroup(level string, samples []models.Listing) *group {
+	if len(samples) < minSamples {
+		return nil
+	}
+	g := &group{level: level, samples: samples, known: make(map[category]bool)}
+
+	var milesN int
+	for _, l := range samples {
+		g.meanYear += float64(*l.Year)
+		if l.Mileage != nil {
+			g.meanMiles += logMiles(*l.Mileage)
+			milesN++
+		}
+	}
+	g.meanYear /= float64(len(samples))
+	if milesN > 0 {
+		g.meanMiles /= float64(milesN)
+	}
+	if len(samples) >= minDummySamples {
+		g.addDummies(true)
+		g.addDummies(false)
+	}
+
+	x := make([][]float64, len(samples))
+	y := make([]float64, len(samples))
+	for i, l := range samples {
+		x[i] = g.features(queryOf(l))
+		y[i] = math.Log(l.Price)
+	}
+	g.coef, g.inv = ridgeSolve(x, y, ridge)
+
+	var sse float64
+	for i := range x {
+		r := y[i] - dot(x[i], g.coef)
+		sse += r * r
+	}
+	g.sigma = max(math.Sqrt(sse/float64(max(len(samples)-len(g.coef), 1))), minSigma)
+	return g
+}
+
+// addDummies adds a column for every body type (or condition) seen at least
+// minCategorySamples times except the most common, which is the baseline.
+func (g *group) addDummies(body bool) {
+	counts := make(map[string]int)
+	var known int
+	for _, l := range g.samples {
+		if v := queryOf(l).field(body); v != "" {
+			counts[v]++
+			known++
+		}
+	}
+	if known == 0 {
+		return
+	}
+
+	values := slices.Sorted(maps.Keys(counts))
+	baseline := slices.MaxFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[a], counts[b]), cmp.Compare(b, a))
+	})
+	g.known[category{body, baseline}] = true
+	for _, v := range values {
+		if v == baseline || counts[v] < minCategorySamples {
+			continue
+		}
+		c := category{body, v}
+		g.known[c] = true
+		g.dummies = append(g.dummies, dummy{category: c, mean: float64(counts[v]) / float64(known)})
+	}
+}
+
+// features returns the design row for q: intercept, centred year, centred
+// log mileage, then one value per dummy column.
+func (g *group) features(q Query) []float64 {
+	x := []float64{1, float64(q.Year) - g.meanYear, 0}
+	if q.Mileage != nil {
+		x[2] = logMiles(*q.Mileage) - g.meanMiles
+	}
+	for _, d := range g.dummies {
+		v := q.field(d.body)
+		switch {
+		case !g.known[category{d.body, v}]:
+			x = append(x, d.mean)
+		case v == d.value:
+			x = append(x, 1)
+		default:
+			x = append(x, 0)
+		}
+	}
+	return x
+}
+
+// predict returns the fitted log price for q and the standard error of a
+// new listing's log price around it. The error grows with q's leverage
+// x'(XᵀX + λI)⁻¹x, so a car far from the group's typical year or mileage
+// gets a wider interval.
+func (g *group) predict(q Query) (logPrice, stdErr float64) {
+	x := g.features(q)
+	logPrice = dot(x, g.coef)
+	var leverage float64
+	for i, row := range g.inv {
+		leverage += x[i] * dot(row, x)
+	}
+	stdErr = g.sigma * math.Sqrt(1+max(leverage, 0))
+	return logPrice, stdErr
+}
+
+// comparables returns the group's listings most like q: those sharing its
+// make and model first, then nearest in year and mileage.
+func (g *group) comparables(q Query) []models.Comparable {
+	nearest := slices.Clone(g.samples)
+	slices.SortFunc(nearest, func(a, b models.Listing) int {
+		return cmp.Or(
+			cmp.Compare(mismatches(q, a), mismatches(q, b)),
+			cmp.Compare(distance(q, a), distance(q, b)),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	out := make([]models.Comparable, 0, maxComparables)
+	for _, l := range nearest[:min(maxComparables, len(nearest))] {
+		out = append(out, models.Comparable{
+			ID:         l.ID,
+			ExternalID: l.ExternalID,
+			Title:      l.Title,
+			URL:        l.URL,
+			Year:       l.Year,
+			Mileage:    l.Mileage,
+			Price:      l.Price,
+		})
+	}
+	return out
+}
+
+// mismatches counts which of q's make and model l does not share.
+func mismatches(q Query, l models.Listing) int {
+	lq := queryOf(l)
+	n := 0
+	if lq.Make != q.Make {
+		n++
+	}
+	if lq.Make != q.Make || lq.Model != q.Model {
+		n++
+	}
+	return n
+}
+
+// distance is how far l is from q in model years, counting milesPerYear of
+// mileage difference as one year. An unknown mileage on either side adds
+// one year.
+func distance(q Query, l models.Listing) float64 {
+	d := math.Abs(float64(q.Year - *l.Year))
+	if q.Mileage != nil && l.Mileage != nil {
+		return d + math.Abs(float64(*q.Mileage-*l.Mileage))/milesPerYear
+	}
+	return d + 1
+}
+
+// logMiles is the mileage feature: log(1 + mileage/1000).
+func logMiles(mileage int) float64 {
+	return math.Log1p(float64(max(mileage, 0)) / 1000)
+}
+
+// ridgeSolve returns the coefficients minimizing |y - xβ|² + λ|β₁..|², the
+// intercept β₀ unpenalized, by solving the normal equations, together with
+// the inverse of their matrix xᵀx + λI.
+func ridgeSolve(x [][]float64, y []float64, lambda float64) (beta []float64, inv [][]float64) {
+	p := len(x[0])
+	a := make([][]float64, p)
+	for i := range a {
+		a[i] = make([]float64, p)
+	}
+	b := make([]float64, p)
+	for r, row := range x {
+		for i := range p {
+			for j := range p {
+				a[i][j] += row[i] * row[j]
+			}
+			b[i] += row[i] * y[r]
+		}
+	}
+	for i := 1; i < p; i++ {
+		a[i][i] += lambda
+	}
+
+	inv = invert(a)
+	beta = make([]float64, p)
+	for i, row := range inv {
+		beta[i] = dot(row, b)
+	}
+	return beta, inv
+}
+
+// invert returns the inverse of the square matrix a by Gauss-Jordan
+// elimination with partial pivoting, overwriting a. a must be non-singular;
+// ridgeSolve's always is.
+func invert(a [][]float64) [][]float64 {
+	p := len(a)
+	inv := make([][]float64, p)
+	for i := range inv {
+		inv[i] = make([]float64, p)
+		inv[i][i] = 1
+	}
+	for col := range p {
+		pivot := col
+		for r := col + 1; r < p; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		d := a[col][col]
+		for c := range p {
+			a[col][c] /= d
+			inv[col][c] /= d
+		}
+		for r := range p {
+			if r == col || a[r][col] == 0 {
+				continue
+			}
+			f := a[r][col]
+			for c := range p {
+				a[r][c] -= f * a[col][c]
+				inv[r][c] -= f * inv[col][c]
+			}
+		}
+	}
+	return inv
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package valuation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+func TestRidgeSolveInverse(t *testing.T) {
+	x := [][]float64{{1, -2, 0.5}, {1, -1, -0.3}, {1, 0, 0.1}, {1, 1, 0.7}, {1, 2, -0.4}}
+	y := []float64{9.1, 9.3, 9.6, 9.8, 10.1}
+
+	beta, inv := ridgeSolve(x, y, ridge)
+
+	// Rebuild xᵀx + λI and xᵀy to check inv is the inverse and beta the
+	// solution of the normal equations.
+	p := len(beta)
+	a := make([][]float64, p)
+	b := make([]float64, p)
+	for i := range p {
+		a[i] = make([]float64, p)
+		for r, row := range x {
+			for j := range p {
+				a[i][j] += row[i] * row[j]
+			}
+			b[i] += row[i] * y[r]
+		}
+		if i > 0 {
+			a[i][i] += ridge
+		}
+	}
+	for i := range p {
+		if got := dot(a[i], beta); math.Abs(got-b[i]) > 1e-9 {
+			t.Errorf("normal equation %d: %g, want %g", i, got, b[i])
+		}
+		for j := range p {
+			var got float64
+			for k := range p {
+				got += a[i][k] * inv[k][j]
+			}
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("(A·inv)[%d][%d] = %g, want %g", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestPredictWidensAwayFromMean(t *testing.T) {
+	var samples []models.Listing
+	for i := range 12 {
+		year := 2014 + i%6
+		miles := 20000 + 8000*i
+		samples = append(samples, models.Listing{
+			ID:      fmt.Sprint(i),
+			Year:    &year,
+			Mileage: &miles,
+			Price:   30000 * math.Pow(0.88, float64(2020-year)) * (1 - float64(i%3)*0.02),
+		})
+	}
+	g := newGroup(models.ValuationLevelMakeModel, samples)
+	if g == nil {
+		t.Fatal("group not fitted")
+	}
+
+	miles := int(math.Expm1(g.meanMiles) * 1000)
+	_, center := g.predict(Query{Year: int(math.Round(g.meanYear)), Mileage: &miles})
+	far := 250000
+	_, edge := g.predict(Query{Year: 2003, Mileage: &far})
+
+	if center < g.sigma {
+		t.Errorf("stdErr at the mean = %g, want at least sigma %g", center, g.sigma)
+	}
+	if edge <= center {
+		t.Errorf("stdErr far from the mean = %g, want more than %g at the mean", edge, center)
+	}
+}
+
+// constantPriceListings returns n listings of one car all asking the same
+// price, which a regression fits exactly.
+func constantPriceListings(n int) []models.Listing {
+	var samples []models.Listing
+	for i := range n {
+		year := 2015 + i%4
+		miles := 30000 + 5000*i
+		samples = append(samples, models.Listing{
+			ID: fmt.Sprint(i), Category: Category, Make: "Toyota", Model: "Corolla",
+			Year: &year, Mileage: &miles, Price: 12000,
+		})
+	}
+	return samples
+}
+
+func TestConstantPriceInterval(t *testing.T) {
+	samples := constantPriceListings(10)
+	g := newGroup(models.ValuationLevelMakeModel, samples)
+	if g == nil {
+		t.Fatal("group not fitted")
+	}
+	if g.sigma < minSigma {
+		t.Errorf("sigma = %g, want at least %g", g.sigma, minSigma)
+	}
+
+	v, err := Fit(samples, time.Now()).Estimate(Query{Make: "Toyota", Model: "Corolla", Year: 2016})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(v.Low < v.Estimate && v.Estimate < v.High) {
+		t.Errorf("interval = [%g, %g] around %g, want a non-empty interval", v.Low, v.High, v.Estimate)
+	}
+}
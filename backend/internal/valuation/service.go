@@ -0,0 +1,131 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appdb "ecaycar/backend/internal/db"
+	"ecaycar/backend/models"
+)
+
+// Category is the listings category valued: the model is fitted on active
+// listings in it.
+const Category = "autos"
+
+// RefreshInterval is how often Run checks the scrape_runs ledger for a newly
+// finished run.
+const RefreshInterval = time.Minute
+
+// maxFitAge is the oldest an Estimator may get before Refresh refits it even
+// though no new scrape run has finished, so listings written outside a
+// recorded run (a reparse, or a run whose ledger update failed) still reach
+// the model.
+const maxFitAge = time.Hour
+
+// ErrNotFitted means no Estimator has been fitted yet, e.g. the API has just
+// started and the first background fit is still running.
+var ErrNotFitted = errors.New("valuation model is not fitted yet")
+
+// Service serves estimates from an Estimator fitted on the store's active
+// listings. Run refits it in the background whenever a scrape run has
+// finished since the last fit, and at least every maxFitAge; cmd/scraper and
+// the API are separate processes, so the scrape_runs ledger is what tells the
+// API a run has happened. Estimate only loads the current Estimator, so requests never
+// wait on the ledger or a refit. It is safe for concurrent use.
+type Service struct {
+	store     appdb.ListingStore
+	estimator atomic.Pointer[Estimator]
+
+	mu    sync.Mutex // serialises refits
+	runID string     // newest finished scrape run when estimator was fitted
+	now   func() time.Time
+}
+
+// NewService returns a Service over store. Nothing is fitted until Refresh
+// or Run is called.
+func NewService(store appdb.ListingStore) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// Estimate values q with the current Estimator. It returns ErrNotFitted
+// before the first fit has finished.
+func (s *Service) Estimate(q Query) (models.Valuation, error) {
+	e := s.estimator.Load()
+	if e == nil {
+		return models.Valuation{}, ErrNotFitted
+	}
+	return e.Estimate(q)
+}
+
+// Run calls Refresh straight away and then every interval until ctx is
+// cancelled. Errors are logged; the previous Estimator keeps serving.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("valuation: refresh: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Refresh refits the Estimator when none has been fitted yet, a scrape run
+// has finished since the last fit or the fit is older than maxFitAge, then
+// swaps it in.
+func (s *Service) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runID, err := lastFinishedRun(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if e := s.estimator.Load(); e != nil && runID == s.runID && now.Sub(e.FittedAt()) < maxFitAge {
+		return nil
+	}
+
+	e, err := FitStore(ctx, s.store, now)
+	if err != nil {
+		return err
+	}
+	s.estimator.Store(e)
+	s.runID = runID
+	return nil
+}
+
+// FitStore fits an Estimator on the active listings of Category in store,
+// stamped as fitted at now.
+func FitStore(ctx context.Context, store appdb.ListingStore, now time.Time) (*Estimator, error) {
+	listings, _, err := store.GetListings(ctx, appdb.ListingFilter{Category: Category})
+	if err != nil {
+		return nil, fmt.Errorf("load listings for valuation: %w", err)
+	}
+	return Fit(listings, now), nil
+}
+
+// lastFinishedRun returns the ID of the newest finished scrape run, or "" if
+// none has finished. A run still in progress is passed over, so the model is
+// not refitted on its half-written results.
+func lastFinishedRun(ctx context.Context, store appdb.ListingStore) (string, error) {
+	runs, err := store.GetScrapeRuns(ctx, 2)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range runs {
+		if r.FinishedAt != nil {
+			return r.ID, nil
+		}
+	}
+	return "", nil
+}
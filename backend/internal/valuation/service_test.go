@@ -0,0 +1,44 @@
+package valuation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appdb "ecaycar/backend/internal/db"
+)
+
+func TestRefreshRefitsStaleModel(t *testing.T) {
+	ctx := context.Background()
+	store := appdb.NewMemoryStore()
+	if _, err := store.UpsertListings(ctx, constantPriceListings(10)); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewService(store)
+	s.now = func() time.Time { return clock }
+
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	first := s.estimator.Load()
+
+	// No scrape run has finished since: a fresh fit is kept...
+	clock = clock.Add(maxFitAge / 2)
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.estimator.Load() != first {
+		t.Error("refitted a model younger than maxFitAge")
+	}
+
+	// ...and a stale one is refitted anyway.
+	clock = clock.Add(maxFitAge)
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e := s.estimator.Load(); e == first || !e.FittedAt().Equal(clock) {
+		t.Errorf("stale model not refitted: fitted at %v, now %v", e.FittedAt(), clock)
+	}
+}
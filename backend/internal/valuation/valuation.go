@@ -0,0 +1,216 @@
+// Package valuation estimates fair asking prices from stored listings. It
+// fits log-linear regressions of price on model year, mileage, body type and
+// condition per make and model, with make-level, segment-level (body type)
+// and market-wide models to fall back on when a make or model has too few
+// listings.
+package valuation
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"ecaycar/backend/models"
+)
+
+// ErrNoModel is returned when there are too few listings to fit even the
+// market-wide model.
+var ErrNoModel = errors.New("not enough listings to estimate a price")
+
+// highConfidenceSamples is the fewest listings behind a make/model estimate
+// rated high confidence (or a make-level one rated medium).
+const highConfidenceSamples = 20
+
+// Query describes the vehicle to value. Year is required. Text fields match
+// case-insensitively and may be empty when unknown, as may Mileage.
+type Query struct {
+	Make      string
+	Model     string
+	Year      int
+	Mileage   *int
+	BodyType  string
+	Condition string
+}
+
+// queryOf describes a listing as a Query. l.Year must be set.
+func queryOf(l models.Listing) Query {
+	return Query{
+		Make:      l.Make,
+		Model:     l.Model,
+		Year:      *l.Year,
+		Mileage:   l.Mileage,
+		BodyType:  l.BodyType,
+		Condition: l.Condition,
+	}.normalized()
+}
+
+// normalized lower-cases and trims q's text fields.
+func (q Query) normalized() Query {
+	for _, s := range []*string{&q.Make, &q.Model, &q.BodyType, &q.Condition} {
+		*s = strings.ToLower(strings.TrimSpace(*s))
+	}
+	return q
+}
+
+// field returns q's body type (body true) or condition.
+func (q Query) field(body bool) string {
+	if body {
+		return q.BodyType
+	}
+	return q.Condition
+}
+
+// Estimator holds the models fitted over one set of listings. It is
+// read-only once built and safe for concurrent use.
+type Estimator struct {
+	fittedAt   time.Time
+	makeModels map[[2]string]*group // keyed by make, model
+	makes      map[string]*group
+	segments   map[string]*group // keyed by body type
+	market     *group
+	// bodyTypes is the most common body type of each make/model (and of
+	// each make, under an empty model), standing in for a query's unknown
+	// body type.
+	bodyTypes map[[2]string]string
+}
+
+// Fit builds an Estimator from listings. Listings without a model year or a
+// positive price are ignored.
+func Fit(listings []models.Listing, now time.Time) *Estimator {
+	var (
+		market     []models.Listing
+		makeModels = make(map[[2]string][]models.Listing)
+		makes      = make(map[string][]models.Listing)
+		segments   = make(map[string][]models.Listing)
+		bodyCounts = make(map[[2]string]map[string]int)
+	)
+	countBody := func(key [2]string, body string) {
+		if bodyCounts[key] == nil {
+			bodyCounts[key] = make(map[string]int)
+		}
+		bodyCounts[key][body]++
+	}
+	for _, l := range listings {
+		if l.Year == nil || l.Price <= 0 {
+			continue
+		}
+		q := queryOf(l)
+		market = append(market, l)
+		if q.BodyType != "" {
+			segments[q.BodyType] = append(segments[q.BodyType], l)
+		}
+		if q.Make == "" {
+			continue
+		}
+		makes[q.Make] = append(makes[q.Make], l)
+		if q.BodyType != "" {
+			countBody([2]string{q.Make, ""}, q.BodyType)
+		}
+		if q.Model != "" {
+			key := [2]string{q.Make, q.Model}
+			makeModels[key] = append(makeModels[key], l)
+			if q.BodyType != "" {
+				countBody(key, q.BodyType)
+			}
+		}
+	}
+
+	e := &Estimator{
+		fittedAt:   now,
+		makeModels: fitGroups(models.ValuationLevelMakeModel, makeModels),
+		makes:      fitGroups(models.ValuationLevelMake, makes),
+		segments:   fitGroups(models.ValuationLevelSegment, segments),
+		market:     newGroup(models.ValuationLevelMarket, market),
+		bodyTypes:  make(map[[2]string]string, len(bodyCounts)),
+	}
+	for key, counts := range bodyCounts {
+		e.bodyTypes[key] = slices.MaxFunc(slices.Sorted(maps.Keys(counts)), func(a, b string) int {
+			return cmp.Or(cmp.Compare(counts[a], counts[b]), cmp.Compare(b, a))
+		})
+	}
+	return e
+}
+
+// fitGroups fits a group per key, leaving out those with too few listings.
+func fitGroups[K comparable](level string, byKey map[K][]models.Listing) map[K]*group {
+	groups := make(map[K]*group)
+	for key, samples := range byKey {
+		if g := newGroup(level, samples); g != nil {
+			groups[key] = g
+		}
+	}
+	return groups
+}
+
+// FittedAt reports when the Estimator was built.
+func (e *Estimator) FittedAt() time.Time {
+	return e.fittedAt
+}
+
+// Estimate values the vehicle described by q with the most specific model
+// available: its make and model, its make, its body type segment, then the
+// whole market. A query without a body type takes the most common one for
+// its make and model.
+func (e *Estimator) Estimate(q Query) (models.Valuation, error) {
+	q = e.withBodyType(q.normalized())
+	g := e.pick(q)
+	if g == nil {
+		return models.Valuation{}, ErrNoModel
+	}
+
+	logPrice, stdErr := g.predict(q)
+	half := z90 * stdErr
+	return models.Valuation{
+		Estimate:    math.Round(math.Exp(logPrice)),
+		Low:         math.Round(math.Exp(logPrice - half)),
+		High:        math.Round(math.Exp(logPrice + half)),
+		Confidence:  confidence(g),
+		Level:       g.level,
+		SampleSize:  len(g.samples),
+		Comparables: g.comparables(q),
+		FittedAt:    e.fittedAt,
+	}, nil
+}
+
+// withBodyType fills in an unknown body type with the most common one for
+// q's make and model.
+func (e *Estimator) withBodyType(q Query) Query {
+	if q.BodyType == "" {
+		q.BodyType = e.bodyTypes[[2]string{q.Make, q.Model}]
+	}
+	return q
+}
+
+// pick returns the most specific fitted group for q, or nil if none is.
+func (e *Estimator) pick(q Query) *group {
+	if g := e.makeModels[[2]string{q.Make, q.Model}]; g != nil {
+		return g
+	}
+	if g := e.makes[q.Make]; g != nil {
+		return g
+	}
+	body := cmp.Or(q.BodyType, e.bodyTypes[[2]string{q.Make, ""}])
+	if g := e.segments[body]; g != nil {
+		return g
+	}
+	return e.market
+}
+
+// confidence rates an estimate by how specific its model is and how many
+// listings it was fitted on.
+func confidence(g *group) string {
+	n := len(g.samples)
+	switch {
+	case g.level == models.ValuationLevelMakeModel && n >= highConfidenceSamples:
+		return models.ConfidenceHigh
+	case g.level == models.ValuationLevelMakeModel,
+		g.level == models.ValuationLevelMake && n >= highConfidenceSamples:
+		return models.ConfidenceMedium
+	default:
+		return models.ConfidenceLow
+	}
+}
@@ -3,37 +3,123 @@ package models
 
 import "time"
 
-// Listing represents a single car listing scraped from ecaytrade.com.
-// Fields map 1-to-1 with the `listings` table in Supabase.
+// Listing represents a single vehicle listing scraped from ecaytrade.com.
+// Fields map 1-to-1 with the `listings` table in Supabase. Attributes holds
+// category-specific detail fields (e.g. a boat's length or engine hours).
+// SellerType is read from the seller's row in `sellers`; SellerMarkers are the
+// profile badges seen on the detail page and are stored on the seller, not
+// the listing. ExpectedPrice, DealScore and DealLabel are written by
+// cmd/scraper's deal scoring, not scraped. CardTitle is the title as parsed
+// from the listings card, before AI enrichment rewrites Title. CardOnly marks
+// a listing whose detail fetch failed, so it carries only card-level fields
+// and the stores keep the stored detail-page values. PostedSlack and
+// UpdatedSlack are the resolution of PostedAt and AdUpdatedAt when the page
+// showed a relative date ("3 weeks ago"), and zero for absolute ones.
 type Listing struct {
-	ID            string     `json:"id,omitempty"`
-	ExternalID    string     `json:"external_id"`
-	URL           string     `json:"url"`
-	Title         string     `json:"title"`
-	Make          string     `json:"make,omitempty"`
-	Model         string     `json:"model,omitempty"`
-	Year          *int       `json:"year,omitempty"`
-	Mileage       *int       `json:"mileage,omitempty"`
-	Price         float64    `json:"price"`
-	Currency      string     `json:"currency"`
-	Condition     string     `json:"condition,omitempty"`
-	Transmission  string     `json:"transmission,omitempty"`
-	FuelType      string     `json:"fuel_type,omitempty"`
-	Color         string     `json:"color,omitempty"`
-	BodyType      string     `json:"body_type,omitempty"`
-	Drive         string     `json:"drive,omitempty"`
-	Cylinders     string     `json:"cylinders,omitempty"`
-	Steering      string     `json:"steering,omitempty"`
-	InteriorColor string     `json:"interior_color,omitempty"`
-	Doors         string     `json:"doors,omitempty"`
-	OnIsland      *bool      `json:"on_island,omitempty"`
-	Description   string     `json:"description,omitempty"`
-	Images        []string   `json:"images,omitempty"`
-	Location      string     `json:"location,omitempty"`
-	SellerName    string     `json:"seller_name,omitempty"`
-	IsActive      bool       `json:"is_active"`
-	FirstSeen     *time.Time `json:"first_seen,omitempty"`
-	LastSeen      *time.Time `json:"last_seen,omitempty"`
-	CreatedAt     *time.Time `json:"created_at,omitempty"`
-	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	ID            string            `json:"id,omitempty"`
+	ExternalID    string            `json:"external_id"`
+	Category      string            `json:"category"`
+	URL           string            `json:"url"`
+	Title         string            `json:"title"`
+	CardTitle     string            `json:"-"`
+	Make          string            `json:"make,omitempty"`
+	Model         string            `json:"model,omitempty"`
+	Year          *int              `json:"year,omitempty"`
+	Mileage       *int              `json:"mileage,omitempty"`
+	Price         float64           `json:"price"`
+	Currency      string            `json:"currency"`
+	Condition     string            `json:"condition,omitempty"`
+	Transmission  string            `json:"transmission,omitempty"`
+	FuelType      string            `json:"fuel_type,omitempty"`
+	Color         string            `json:"color,omitempty"`
+	BodyType      string            `json:"body_type,omitempty"`
+	Drive         string            `json:"drive,omitempty"`
+	Cylinders     string            `json:"cylinders,omitempty"`
+	Steering      string            `json:"steering,omitempty"`
+	InteriorColor string            `json:"interior_color,omitempty"`
+	Doors         string            `json:"doors,omitempty"`
+	OnIsland      *bool             `json:"on_island,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Images        []string          `json:"images,omitempty"`
+	Location      string            `json:"location,omitempty"`
+	SellerName    string            `json:"seller_name,omitempty"`
+	SellerURL     string            `json:"seller_url,omitempty"`
+	SellerType    string            `json:"seller_type,omitempty"`
+	SellerMarkers []string          `json:"-"`
+	PostedAt      *time.Time        `json:"posted_at,omitempty"`
+	AdUpdatedAt   *time.Time        `json:"ad_updated_at,omitempty"`
+	PostedSlack   time.Duration     `json:"-"`
+	UpdatedSlack  time.Duration     `json:"-"`
+	IsActive      bool              `json:"is_active"`
+	FirstSeen     *time.Time        `json:"first_seen,omitempty"`
+	LastSeen      *time.Time        `json:"last_seen,omitempty"`
+	DelistedAt    *time.Time        `json:"delisted_at,omitempty"`
+	CreatedAt     *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time        `json:"updated_at,omitempty"`
+	ExpectedPrice *float64          `json:"expected_price,omitempty"`
+	DealScore     *float64          `json:"deal_score,omitempty"`
+	DealLabel     string            `json:"deal_label,omitempty"`
+	CardOnly      bool              `json:"-"`
+}
+
+// ListingSnapshot holds the raw inputs the parser saw for one advert card in
+// one scrape run: the card text from the listings page and, when the detail
+// page was fetched, its HTML, innerText, extracted "Ad Details" map and
+// gallery image URLs.
+// Snapshots are archived so history can be re-parsed after parser fixes.
+type ListingSnapshot struct {
+	ID           string            `json:"id,omitempty"`
+	RunID        string            `json:"run_id,omitempty"`
+	ExternalID   string            `json:"external_id"`
+	Category     string            `json:"category,omitempty"`
+	URL          string            `json:"url"`
+	CardText     string            `json:"card_text"`
+	ImgSrc       string            `json:"img_src,omitempty"`
+	DetailHTML   string            `json:"detail_html,omitempty"`
+	DetailText   string            `json:"detail_text,omitempty"`
+	DetailFields map[string]string `json:"detail_fields,omitempty"`
+	DetailImages []string          `json:"detail_images,omitempty"`
+	CapturedAt   *time.Time        `json:"captured_at,omitempty"`
+}
+
+// PricePoint is one row of a listing's price_history: the asking price and
+// when it was observed.
+type PricePoint struct {
+	Price      float64   `json:"price"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ListingDetail is one listing with what its detail page shows alongside it:
+// the asking-price series oldest first, time on market, and the most similar
+// active listings.
+type ListingDetail struct {
+	Listing
+	PriceHistory []PricePoint `json:"price_history"`
+	DaysOnMarket float64      `json:"days_on_market"`
+	Similar      []Listing    `json:"similar"`
+}
+
+// FieldChange describes one listing field whose value differs between two
+// versions of a listing. Values are rendered as display strings; an empty
+// string means the field was unset.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ListingChange is one field-level change recorded in listing_changes when a
+// scrape found a stored listing's field with a different value.
+type ListingChange struct {
+	FieldChange
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// KnownListing is the stored fingerprint of a listing that incremental scrapes
+// compare against a fresh card to decide whether its detail page needs
+// re-fetching. Title is the stored card title, never the enriched one.
+type KnownListing struct {
+	Price float64
+	Title string
 }
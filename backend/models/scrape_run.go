@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Scrape run statuses stored in scrape_runs.status.
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+	RunStatusBlocked   = "blocked" // stopped by a bot challenge, not by the end of results
+)
+
+// ScrapeRun is one row of the scrape_runs ledger: per-run counters recorded by
+// cmd/scraper so silent degradation (fewer pages, more skips, failing detail
+// pages) is visible after the fact.
+type ScrapeRun struct {
+	ID                    string     `json:"id,omitempty"`
+	StartedAt             time.Time  `json:"started_at"`
+	FinishedAt            *time.Time `json:"finished_at,omitempty"`
+	Status                string     `json:"status"`
+	Complete              bool       `json:"complete"`
+	PagesVisited          int        `json:"pages_visited"`
+	RawCards              int        `json:"raw_cards"`
+	Accepted              int        `json:"accepted"`
+	Unchanged             int        `json:"unchanged"`
+	SkippedEmpty          int        `json:"skipped_empty"`
+	SkippedNoYear         int        `json:"skipped_no_year"`
+	SkippedLowPrice       int        `json:"skipped_low_price"`
+	SkippedPriceOnRequest int        `json:"skipped_price_on_request"`
+	DetailFailures        int        `json:"detail_failures"`
+	BlockedPages          int        `json:"blocked_pages"`
+	EnrichCalls           int        `json:"enrich_calls"`
+	EnrichFailures        int        `json:"enrich_failures"`
+	Inserted              int        `json:"inserted"`
+	Updated               int        `json:"updated"`
+	PriceChanged          int        `json:"price_changed"`
+	UpsertErrors          int        `json:"upsert_errors"`
+	Delisted              int        `json:"delisted"`
+	Error                 string     `json:"error,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Seller types stored in sellers.seller_type.
+const (
+	SellerTypeDealer  = "dealer"
+	SellerTypePrivate = "private"
+)
+
+// Seller is one ecaytrade seller profile, keyed by its profile URL. The
+// inventory and price figures are computed from the seller's listings when
+// the seller is read; they are not stored.
+type Seller struct {
+	ID              string     `json:"id,omitempty"`
+	ProfileURL      string     `json:"profile_url"`
+	Name            string     `json:"name"`
+	SellerType      string     `json:"seller_type"`
+	ProfileMarkers  []string   `json:"profile_markers,omitempty"`
+	ListingCount    int        `json:"listing_count"`
+	InventoryCount  int        `json:"inventory_count"`
+	AvgPrice        float64    `json:"avg_price"`
+	AvgDaysOnMarket float64    `json:"avg_days_on_market"`
+	FirstSeen       *time.Time `json:"first_seen,omitempty"`
+	LastSeen        *time.Time `json:"last_seen,omitempty"`
+}
@@ -31,3 +31,15 @@ type YearStat struct {
 	Year  int `json:"year"`
 	Count int `json:"count"`
 }
+
+// TrendBucket holds market figures for one day, week or month. Inventory and
+// the prices describe the listings live on the bucket's last recorded day;
+// New and Delisted count listings first seen and delisted within it.
+type TrendBucket struct {
+	Bucket      string  `json:"bucket"` // first day, YYYY-MM-DD
+	Inventory   int     `json:"inventory"`
+	New         int     `json:"new"`
+	Delisted    int     `json:"delisted"`
+	MedianPrice float64 `json:"median_price"`
+	AvgPrice    float64 `json:"avg_price"`
+}
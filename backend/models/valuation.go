@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Valuation model levels, from most to least specific. An estimate uses the
+// most specific level with enough listings behind it.
+const (
+	ValuationLevelMakeModel = "make_model"
+	ValuationLevelMake      = "make"
+	ValuationLevelSegment   = "segment" // all listings of one body type
+	ValuationLevelMarket    = "market"
+)
+
+// Valuation confidence tiers.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Deal labels stored in listings.deal_label, from best to worst.
+const (
+	DealGreat = "great"
+	DealGood  = "good"
+	DealFair  = "fair"
+	DealHigh  = "high"
+)
+
+// DealScore is how one listing's asking price compares with the price the
+// valuation model expects for it. Score is a percentile from 0 to 100: the
+// share of comparable listings expected to be priced higher, so higher is a
+// better deal.
+type DealScore struct {
+	ListingID     string
+	ExpectedPrice float64
+	Score         float64
+	Label         string
+}
+
+// Valuation is a fair-price estimate for one vehicle. Low and High bound the
+// 90% prediction interval; SampleSize is the number of listings the model was
+// fitted on and Comparables the ones among them nearest the vehicle.
+type Valuation struct {
+	Estimate    float64      `json:"estimate"`
+	Low         float64      `json:"low"`
+	High        float64      `json:"high"`
+	Confidence  string       `json:"confidence"`
+	Level       string       `json:"level"`
+	SampleSize  int          `json:"sample_size"`
+	Comparables []Comparable `json:"comparables"`
+	FittedAt    time.Time    `json:"fitted_at"`
+}
+
+// Comparable is a listing a valuation was fitted on, reduced to what is
+// shown beside the estimate.
+type Comparable struct {
+	ID         string  `json:"id"`
+	ExternalID string  `json:"external_id"`
+	Title      string  `json:"title"`
+	URL        string  `json:"url"`
+	Year       *int    `json:"year,omitempty"`
+	Mileage    *int    `json:"mileage,omitempty"`
+	Price      float64 `json:"price"`
+}
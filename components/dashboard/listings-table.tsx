@@ -1,6 +1,6 @@
 "use client"
 
-import { useState, useMemo } from "react"
+import { useState, useMemo, useEffect, useRef } from "react"
 import {
   Table,
   TableBody,
@@ -20,12 +20,22 @@ import {
   SelectValue,
 } from "@/components/ui/select"
 import { carListings } from "@/lib/mock-data"
-import type { ApiListing } from "@/lib/api"
-import { Search, ArrowUpDown, ExternalLink } from "lucide-react"
+import { Button } from "@/components/ui/button"
+import { fetchListings, LISTINGS_PAGE_SIZE } from "@/lib/api"
+import type { ApiListing, ListingsPage, ListingsSort } from "@/lib/api"
+import { Search, ArrowUpDown, ExternalLink, ChevronLeft, ChevronRight } from "lucide-react"
 
 type SortField = "price" | "year" | "mileage" | "listedDate"
 type SortOrder = "asc" | "desc"
 
+// apiSort maps the table's sort columns onto /api/listings ?sort= keys.
+const apiSort: Record<SortField, ListingsSort> = {
+  price: "price",
+  year: "year",
+  mileage: "mileage",
+  listedDate: "first_seen",
+}
+
 interface DisplayListing {
   id: string
   make: string
@@ -96,26 +106,63 @@ function mapMockListings(): DisplayListing[] {
 }
 
 interface ListingsTableProps {
-  initialListings?: ApiListing[]
+  initialPage?: ListingsPage
 }
 
-export function ListingsTable({ initialListings }: ListingsTableProps) {
+// ListingsTable shows one page of listings at a time. With live data the make
+// filter, sort and paging are sent to /api/listings; the search box narrows
+// the page on screen. Without data it falls back to the mock listings.
+export function ListingsTable({ initialPage }: ListingsTableProps) {
   const [search, setSearch] = useState("")
   const [makeFilter, setMakeFilter] = useState("all")
   const [sortField, setSortField] = useState<SortField>("listedDate")
   const [sortOrder, setSortOrder] = useState<SortOrder>("desc")
+  const [offset, setOffset] = useState(0)
+  const [page, setPage] = useState<ListingsPage | undefined>(initialPage)
+  const [loading, setLoading] = useState(false)
+
+  const live = !!initialPage && initialPage.total > 0
+
+  // The first page arrives with the server render; refetch only once the
+  // filters, sort or page change.
+  const firstRender = useRef(true)
+  useEffect(() => {
+    if (firstRender.current) {
+      firstRender.current = false
+      return
+    }
+    if (!live) return
+    let cancelled = false
+    setLoading(true)
+    fetchListings({
+      make: makeFilter === "all" ? undefined : makeFilter,
+      sort: apiSort[sortField],
+      order: sortOrder,
+      offset,
+    }).then((p) => {
+      if (!cancelled) {
+        setPage(p)
+        setLoading(false)
+      }
+    })
+    return () => {
+      cancelled = true
+    }
+  }, [live, makeFilter, sortField, sortOrder, offset])
 
   const allListings = useMemo<DisplayListing[]>(() => {
-    if (initialListings && initialListings.length > 0) {
-      return initialListings.map(mapApiListing)
+    if (live) {
+      return (page?.listings ?? []).map(mapApiListing)
     }
     return mapMockListings()
-  }, [initialListings])
+  }, [live, page])
 
-  const makes = useMemo(
-    () => Array.from(new Set(allListings.map((c) => c.make).filter(Boolean))).sort(),
-    [allListings]
-  )
+  // Offer the makes of the first page so the list does not shrink to the
+  // selected make once the server has filtered by it.
+  const makes = useMemo(() => {
+    const source: { make: string }[] = live ? (initialPage?.listings ?? []) : mapMockListings()
+    return Array.from(new Set(source.map((c) => c.make).filter(Boolean))).sort()
+  }, [live, initialPage])
 
   const filtered = useMemo(() => {
     let items = [...allListings]
@@ -130,6 +177,11 @@ export function ListingsTable({ initialListings }: ListingsTableProps) {
       )
     }
 
+    // Live pages come back filtered and sorted by the server.
+    if (live) {
+      return items
+    }
+
     if (makeFilter !== "all") {
       items = items.filter((c) => c.make === makeFilter)
     }
@@ -155,7 +207,7 @@ export function ListingsTable({ initialListings }: ListingsTableProps) {
     })
 
     return items
-  }, [allListings, search, makeFilter, sortField, sortOrder])
+  }, [allListings, live, search, makeFilter, sortField, sortOrder])
 
   function toggleSort(field: SortField) {
     if (sortField === field) {
@@ -164,8 +216,19 @@ export function ListingsTable({ initialListings }: ListingsTableProps) {
       setSortField(field)
       setSortOrder("desc")
     }
+    setOffset(0)
   }
 
+  function changeMake(make: string) {
+    setMakeFilter(make)
+    setOffset(0)
+  }
+
+  const total = live ? (page?.total ?? 0) : filtered.length
+  const pageSize = page?.limit ?? LISTINGS_PAGE_SIZE
+  const pageStart = live && total > 0 ? offset + 1 : 0
+  const pageEnd = live ? Math.min(offset + pageSize, total) : total
+
   return (
     <Card className="border-border/50">
       <CardHeader className="pb-2">
@@ -173,20 +236,22 @@ export function ListingsTable({ initialListings }: ListingsTableProps) {
           <div>
             <CardTitle className="text-base font-semibold">Recent Listings</CardTitle>
             <CardDescription>
-              {filtered.length} vehicles found on ecaytrade.com
+              {live
+                ? `${pageStart}–${pageEnd} of ${total} vehicles found on ecaytrade.com`
+                : `${filtered.length} vehicles found on ecaytrade.com`}
             </CardDescription>
           </div>
           <div className="flex items-center gap-3">
             <div className="relative">
               <Search className="absolute left-3 top-1/2 size-4 -translate-y-1/2 text-muted-foreground" />
               <Input
-                placeholder="Search make, model..."
+                placeholder={live ? "Search this page..." : "Search make, model..."}
                 value={search}
                 onChange={(e) => setSearch(e.target.value)}
                 className="h-9 w-48 pl-9 bg-secondary border-border/50"
               />
             </div>
-            <Select value={makeFilter} onValueChange={setMakeFilter}>
+            <Select value={makeFilter} onValueChange={changeMake}>
               <SelectTrigger className="h-9 w-36 bg-secondary border-border/50">
                 <SelectValue placeholder="All Makes" />
               </SelectTrigger>
@@ -339,6 +404,28 @@ export function ListingsTable({ initialListings }: ListingsTableProps) {
             })}
           </TableBody>
         </Table>
+        {live && total > pageSize && (
+          <div className="flex items-center justify-end gap-2 border-t border-border/50 px-6 py-3">
+            <Button
+              variant="outline"
+              size="sm"
+              disabled={loading || offset === 0}
+              onClick={() => setOffset(Math.max(offset - pageSize, 0))}
+            >
+              <ChevronLeft className="size-4" />
+              Previous
+            </Button>
+            <Button
+              variant="outline"
+              size="sm"
+              disabled={loading || offset + pageSize >= total}
+              onClick={() => setOffset(offset + pageSize)}
+            >
+              Next
+              <ChevronRight className="size-4" />
+            </Button>
+          </div>
+        )}
       </CardContent>
     </Card>
   )
@@ -67,15 +67,50 @@ export async function fetchStats(): Promise<DashboardStats | null> {
   }
 }
 
-export async function fetchListings(): Promise<ApiListing[]> {
+export type ListingsSort = "price" | "year" | "mileage" | "first_seen" | "deal_score"
+
+// ListingsQuery holds the /api/listings filters the dashboard uses; the
+// server filters, sorts and pages, so only one page is ever downloaded.
+export interface ListingsQuery {
+  make?: string
+  sort?: ListingsSort
+  order?: "asc" | "desc"
+  limit?: number
+  offset?: number
+}
+
+export interface ListingsPage {
+  listings: ApiListing[]
+  total: number
+  limit: number
+  offset: number
+}
+
+export const LISTINGS_PAGE_SIZE = 100
+
+export async function fetchListings(query: ListingsQuery = {}): Promise<ListingsPage> {
+  const limit = query.limit ?? LISTINGS_PAGE_SIZE
+  const offset = query.offset ?? 0
+  const empty: ListingsPage = { listings: [], total: 0, limit, offset }
+
+  const params = new URLSearchParams({ limit: String(limit), offset: String(offset) })
+  if (query.make) params.set("make", query.make)
+  if (query.sort) params.set("sort", query.sort)
+  if (query.order) params.set("order", query.order)
+
   try {
-    const res = await fetch(`${API_BASE}/api/listings`, {
+    const res = await fetch(`${API_BASE}/api/listings?${params}`, {
       next: { revalidate: 60 },
     })
-    if (!res.ok) return []
+    if (!res.ok) return empty
     const json = await res.json()
-    return (json.data ?? []) as ApiListing[]
+    return {
+      listings: (json.data ?? []) as ApiListing[],
+      total: json.total ?? 0,
+      limit: json.limit ?? limit,
+      offset: json.offset ?? offset,
+    }
   } catch {
-    return []
+    return empty
   }
 }